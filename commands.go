@@ -0,0 +1,925 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/llugin/mubi-parser/country"
+	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/feed"
+	"github.com/llugin/mubi-parser/fetch"
+	"github.com/llugin/mubi-parser/history"
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/mubi"
+	"github.com/llugin/mubi-parser/parser"
+	"github.com/llugin/mubi-parser/printer"
+	"github.com/llugin/mubi-parser/score"
+	"github.com/llugin/mubi-parser/stats"
+	"github.com/llugin/mubi-parser/tui"
+	"github.com/llugin/mubi-parser/watchlist"
+	"github.com/llugin/mubi-parser/watchlog"
+	"github.com/llugin/mubi-parser/workers"
+)
+
+// command run when no command is given
+const defaultCommand = "update"
+
+// globalFlags are accepted both before and after command name
+type globalFlags struct {
+	stderrLog bool
+	mubiSleep int
+	imdbSleep int
+	noColor   bool
+	// countryStyle is validated after parsing
+	countryStyle string
+	// region is a comma separated list of MUBI regions, parsed to regions
+	region string
+}
+
+var (
+	globals   globalFlags
+	confFlags configFlags
+	// regions are ISO 3166 alpha-2 codes of MUBI regions given with -region
+	regions []string
+)
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&g.stderrLog, "stderr-debug", false, "Print debug info to stderr")
+	fs.IntVar(&g.mubiSleep, "mubi-sleep", 0, "Sleep between mubi HTTP requests in seconds, overrides MubiRate")
+	fs.IntVar(&g.imdbSleep, "imdb-sleep", 0, "Sleep between OMDB API calls in milliseconds, overrides OMDBRate")
+	fs.BoolVar(&g.noColor, "no-color", false, "Disable color output")
+	fs.StringVar(&g.countryStyle, "country-style", country.StyleName,
+		"Display countries as: ["+strings.Join(country.Styles, "|")+"]")
+	fs.StringVar(&g.region, "region", "",
+		"MUBI region as country code or name, like gb. Several comma separated regions are compared by list and update")
+}
+
+// parseRegions parses comma separated countries into alpha-2 codes
+func parseRegions(s string) ([]string, error) {
+	var codes []string
+	for _, name := range movie.ParseList(s) {
+		c, ok := country.Lookup(name)
+		if !ok || c.Historical {
+			return nil, fmt.Errorf("Unknown region: %s", name)
+		}
+		codes = append(codes, c.Alpha2)
+	}
+	return codes, nil
+}
+
+// regionLineups reads lineup of each region with load, restoring current
+// region afterwards. Enrichment errors of all regions are returned joined
+func regionLineups(load func() ([]movie.Data, error)) (map[string][]movie.Data, error) {
+	defer func(current string) { movie.Region = current }(movie.Region)
+	lineups := map[string][]movie.Data{}
+	var failed []error
+	for _, r := range regions {
+		movie.Region = r
+		movies, err := load()
+		if enrichmentFailed(err) {
+			failed = append(failed, fmt.Errorf("Region %s: %w", r, err))
+		} else if err != nil {
+			return nil, fmt.Errorf("Region %s: %w", r, err)
+		}
+		lineups[r] = movies
+	}
+	return lineups, errors.Join(failed...)
+}
+
+type command struct {
+	name  string
+	args  string
+	help  string
+	flags *flag.FlagSet
+	run   func(args []string, conf config) error
+	// multiRegion commands accept several regions
+	multiRegion bool
+}
+
+func newCommand(name, args, help string) *command {
+	c := &command{name: name, args: args, help: help}
+	c.flags = flag.NewFlagSet(name, flag.ExitOnError)
+	c.flags.Usage = func() {
+		out := c.flags.Output()
+		fmt.Fprintf(out, "Usage: %s [global flags] %s %s\n\n%s\n", os.Args[0], c.name, c.args, c.help)
+		fmt.Fprintln(out, "\nFlags:")
+		c.flags.PrintDefaults()
+	}
+	globals.register(c.flags)
+	confFlags.register(c.flags)
+	return c
+}
+
+func findCommand(commands []*command, name string) (*command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+func usage(commands []*command) {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [global flags] <command> [flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-13s %s\n", c.name, c.help)
+	}
+	fmt.Fprintf(out, "\nDefault command is '%s'. Run '%s <command> -h' for command flags.\n", defaultCommand, os.Args[0])
+	fmt.Fprintln(out, "\nGlobal flags:")
+	flag.PrintDefaults()
+}
+
+func addSortFlag(fs *flag.FlagSet) *sortValue {
+	sv := &sortValue{"days", movie.SortByDays, false}
+	fs.Var(sv, "sort", "Sort by: ["+strings.Join(movie.SortKeys, "|")+"], default: days. Add '-' at argument end to reverse order")
+	return sv
+}
+
+// listing orders stored films the way list prints them, so that listing
+// indexes given to other commands point at the films listed
+type listing struct {
+	sort      *sortValue
+	unwatched *bool
+	filter    *filters
+}
+
+func addListingFlags(fs *flag.FlagSet) *listing {
+	return &listing{sort: addSortFlag(fs), unwatched: addUnwatchedFlag(fs), filter: addFilterFlags(fs)}
+}
+
+// apply returns movies as listed, with their watched state set
+func (l *listing) apply(movies []movie.Data) ([]movie.Data, error) {
+	movies, err := applyWatchLog(movies, *l.unwatched)
+	if err != nil {
+		return nil, err
+	}
+	movies = l.filter.apply(movies)
+	l.sort.sort(movies)
+	return movies, nil
+}
+
+func addUnwatchedFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("unwatched", false, "List only films not marked as watched")
+}
+
+// applyWatchLog sets watched state of movies, dropping watched ones if
+// only unwatched movies are requested
+func applyWatchLog(movies []movie.Data, unwatched bool) ([]movie.Data, error) {
+	l, err := watchlog.Read()
+	if err != nil {
+		return nil, err
+	}
+	l.Apply(movies)
+	if !unwatched {
+		return movies, nil
+	}
+	var out []movie.Data
+	for _, m := range movies {
+		if !m.Watched {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// recordOpened adds movie opened in a web browser to watch log
+func recordOpened(m movie.Data) error {
+	l, err := watchlog.Read()
+	if err != nil {
+		return err
+	}
+	l.Opened(m, time.Now())
+	return l.Write()
+}
+
+// filters select movies to list
+type filters struct {
+	minVotes int
+	director string
+	genre    string
+	country  string
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filters {
+	f := &filters{}
+	fs.IntVar(&f.minVotes, "min-votes", 0, "List only films with at least this many MUBI and IMDB votes in total")
+	fs.StringVar(&f.director, "director", "", "List only films with this director among directors")
+	fs.StringVar(&f.genre, "genre", "", "List only films with this genre among genres")
+	fs.StringVar(&f.country, "country", "", "List only films with this country among countries, given by name or ISO code")
+	return f
+}
+
+// apply returns movies matching all filters
+func (f *filters) apply(movies []movie.Data) []movie.Data {
+	var out []movie.Data
+	for _, m := range movies {
+		switch {
+		case m.TotalVotes() < movie.Votes(f.minVotes):
+		case f.director != "" && !m.Directors.Contains(f.director):
+		case f.genre != "" && !m.Genres.Contains(f.genre):
+		case f.country != "" && !m.HasCountry(f.country):
+		default:
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func addExplainFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("explain", false, "Print how each term contributed to score instead of table")
+}
+
+// printMovies prints movies as a table or as explained scores
+func printMovies(movies []movie.Data, maxLen int, explain bool) {
+	if explain {
+		printer.PrintExplain(os.Stdout, movies, maxLen)
+	} else {
+		printer.PrintTable(movies, globals.noColor, maxLen)
+	}
+}
+
+func addMaxLenFlag(fs *flag.FlagSet) *int {
+	return fs.Int("max-len", 32, "Max output table column length. Value equal or less than zero stands for unlimited length.")
+}
+
+func newCommands() []*command {
+	return []*command{
+		listCommand(),
+		updateCommand(),
+		browseCommand(),
+		showCommand(),
+		watchCommand(),
+		markWatchedCommand(),
+		unmarkCommand(),
+		logCommand(),
+		watchlistCommand(),
+		diffCommand(),
+		historyCommand(),
+		statsCommand(),
+		exportCommand(),
+		feedCommand(),
+		configCommand(),
+		doctorCommand(),
+		serveCommand(),
+	}
+}
+
+// printRegions prints which films of lineups are available in which region
+func printRegions(lineups map[string][]movie.Data, ls *listing) error {
+	for r, movies := range lineups {
+		movies, err := ls.apply(movies)
+		if err != nil {
+			return err
+		}
+		lineups[r] = movies
+	}
+	printer.PrintRegions(os.Stdout, regions, lineups)
+	return nil
+}
+
+// update fetches movies from the web with configured selector profile,
+// stores them and records lineup history
+func update(refresh bool, conf config) ([]movie.Data, error) {
+	if err := mubi.UseProfile(conf.SelectorsFile, conf.SelectorProfile); err != nil {
+		return nil, err
+	}
+	movies, enrichErr := parser.GetMovies(refresh)
+	for _, stat := range workers.Report() {
+		log.Println(stat)
+	}
+	// films with failed stages are stored too, to be retried next update
+	if enrichErr != nil && !enrichmentFailed(enrichErr) {
+		return nil, enrichErr
+	}
+	if err := movie.WriteToJSON(movies); err != nil {
+		return nil, err
+	}
+
+	h, err := history.Read()
+	if err != nil {
+		return nil, err
+	}
+	h.Record(movies, time.Now())
+	if err := h.Write(); err != nil {
+		return nil, err
+	}
+	return movies, enrichErr
+}
+
+// enrichmentFailed tells if err is parser.EnrichmentError, returned along
+// with all films when only some of their data is missing
+func enrichmentFailed(err error) bool {
+	var enrichErr *parser.EnrichmentError
+	return errors.As(err, &enrichErr)
+}
+
+func listCommand() *command {
+	c := newCommand("list", "", "Print table of stored films, no web connections are made")
+	ls := addListingFlags(c.flags)
+	maxLen := addMaxLenFlag(c.flags)
+	explain := addExplainFlag(c.flags)
+	c.multiRegion = true
+	c.run = func(args []string, conf config) error {
+		if len(regions) > 1 {
+			lineups, err := regionLineups(movie.ReadFromJSON)
+			if err != nil {
+				return err
+			}
+			return printRegions(lineups, ls)
+		}
+
+		movies, err := movie.ReadFromJSON()
+		if err != nil {
+			return err
+		}
+		wl, err := watchlist.Read()
+		if err != nil {
+			return err
+		}
+		alerts := wl.Check(movies, nil)
+		if movies, err = ls.apply(movies); err != nil {
+			return err
+		}
+		printer.PrintAlerts(os.Stdout, alerts, globals.noColor)
+		printMovies(movies, *maxLen, *explain)
+		return nil
+	}
+	return c
+}
+
+func updateCommand() *command {
+	c := newCommand("update", "", "Fetch currently showing films from the web and print them as a table")
+	refresh := c.flags.Bool("refresh", false, "Refresh all data, not only new movies")
+	ls := addListingFlags(c.flags)
+	maxLen := addMaxLenFlag(c.flags)
+	explain := addExplainFlag(c.flags)
+	c.multiRegion = true
+	c.run = func(args []string, conf config) error {
+		start := time.Now()
+		if len(regions) > 1 {
+			var alerts []watchlist.Alert
+			lineups, err := regionLineups(func() ([]movie.Data, error) {
+				movies, err := update(*refresh, conf)
+				alerts = append(alerts, parser.Alerts...)
+				return movies, err
+			})
+			if err != nil && !enrichmentFailed(err) {
+				return err
+			}
+			log.Printf("Total time: %0.f s\n", time.Since(start).Seconds())
+			printer.PrintAlerts(os.Stdout, alerts, globals.noColor)
+			if err := printRegions(lineups, ls); err != nil {
+				return err
+			}
+			for _, r := range regions {
+				printer.PrintFailures(os.Stderr, lineups[r])
+			}
+			return err
+		}
+
+		movies, enrichErr := update(*refresh, conf)
+		if enrichErr != nil && !enrichmentFailed(enrichErr) {
+			return enrichErr
+		}
+		// summary lists all failed films, also those filtered out
+		all := movies
+		movies, err := ls.apply(movies)
+		if err != nil {
+			return err
+		}
+		printer.PrintAlerts(os.Stdout, parser.Alerts, globals.noColor)
+		printMovies(movies, *maxLen, *explain)
+		printer.PrintFailures(os.Stderr, all)
+		log.Printf("Total time: %0.f s\n", time.Since(start).Seconds())
+		return enrichErr
+	}
+	return c
+}
+
+func browseCommand() *command {
+	c := newCommand("browse", "", "Open interactive terminal browser over stored films")
+	fetch := c.flags.Bool("update", false, "Fetch films from the web before browsing")
+	sv := addSortFlag(c.flags)
+	maxLen := addMaxLenFlag(c.flags)
+	c.run = func(args []string, conf config) error {
+		var movies []movie.Data
+		var err error
+		if *fetch {
+			movies, err = update(false, conf)
+		} else {
+			movies, err = movie.ReadFromJSON()
+		}
+		// films which failed are browsed with their data marked unknown
+		if err != nil && !enrichmentFailed(err) {
+			return err
+		}
+		if movies, err = applyWatchLog(movies, false); err != nil {
+			return err
+		}
+		score.Apply(movies)
+		tui.OnWatch = func(m movie.Data) {
+			if err := recordOpened(m); err != nil {
+				debugging.Log().Println(err)
+			}
+		}
+		return tui.Browse(movies, sv.key, sv.reversed, *maxLen)
+	}
+	return c
+}
+
+func showCommand() *command {
+	c := newCommand("show", "<film>", "Print details of a single film")
+	format := c.flags.String("format", "text", "Output format: [text|json]")
+	ls := addListingFlags(c.flags)
+	c.run = func(args []string, conf config) error {
+		if len(args) == 0 {
+			return fmt.Errorf("show requires film title, director or listing index")
+		}
+		movies, err := movie.ReadFromJSON()
+		if err != nil {
+			return err
+		}
+		if movies, err = ls.apply(movies); err != nil {
+			return err
+		}
+		m, err := pick(movies, strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+
+		h, err := history.Read()
+		if err != nil {
+			return err
+		}
+		windows := h.Films[m.MubiLink].Windows
+
+		switch *format {
+		case "text":
+			printer.PrintDetails(os.Stdout, m, windows)
+			l, err := watchlog.Read()
+			if err != nil {
+				return err
+			}
+			if e, ok := l.Films[m.MubiLink]; ok && e.Watched != "" {
+				fmt.Printf("Watched:    %s", e.Watched)
+				if e.Rating > 0 {
+					fmt.Printf(", rated %g", e.Rating)
+				}
+				if e.Note != "" {
+					fmt.Printf(", %s", e.Note)
+				}
+				fmt.Println()
+			}
+			return nil
+		case "json":
+			return printer.PrintDetailsJSON(os.Stdout, m, windows)
+		default:
+			return fmt.Errorf("Undefined show format: %s", *format)
+		}
+	}
+	return c
+}
+
+func watchCommand() *command {
+	c := newCommand("watch", "<film>",
+		"Open film matching title, alt title, director or '#' index of sorted listing")
+	ls := addListingFlags(c.flags)
+	c.run = func(args []string, conf config) error {
+		if len(args) == 0 {
+			return fmt.Errorf("watch requires film title, director or listing index")
+		}
+		movies, err := movie.ReadFromJSON()
+		if err != nil {
+			return err
+		}
+		if movies, err = ls.apply(movies); err != nil {
+			return err
+		}
+		m, err := pick(movies, strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		if err := m.Watch(); err != nil {
+			return err
+		}
+		return recordOpened(m)
+	}
+	return c
+}
+
+func markWatchedCommand() *command {
+	c := newCommand("mark-watched", "<film>", "Mark film as watched, with optional personal rating and note")
+	rating := c.flags.Float64("rating", 0, fmt.Sprintf("Personal rating from 1 to %.0f", watchlog.MaxRating))
+	note := c.flags.String("note", "", "Personal note")
+	ls := addListingFlags(c.flags)
+	c.run = func(args []string, conf config) error {
+		return updateWatchLog(args, ls, func(l *watchlog.Log, m movie.Data) error {
+			return l.MarkWatched(m, time.Now(), *rating, *note)
+		})
+	}
+	return c
+}
+
+func unmarkCommand() *command {
+	c := newCommand("unmark", "<film>", "Remove watched state, rating and note of film")
+	ls := addListingFlags(c.flags)
+	c.run = func(args []string, conf config) error {
+		return updateWatchLog(args, ls, func(l *watchlog.Log, m movie.Data) error {
+			return l.Unmark(m)
+		})
+	}
+	return c
+}
+
+// updateWatchLog applies change to watch log entry of film picked by args
+func updateWatchLog(args []string, ls *listing, change func(*watchlog.Log, movie.Data) error) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Film title, director or listing index required")
+	}
+	movies, err := movie.ReadFromJSON()
+	if err != nil {
+		return err
+	}
+	if movies, err = ls.apply(movies); err != nil {
+		return err
+	}
+	m, err := pick(movies, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	l, err := watchlog.Read()
+	if err != nil {
+		return err
+	}
+	if err := change(l, m); err != nil {
+		return err
+	}
+	return l.Write()
+}
+
+func logCommand() *command {
+	c := newCommand("log", "", "Print personal watch log")
+	c.run = func(args []string, conf config) error {
+		l, err := watchlog.Read()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "Title\tDirector\tOpened\tWatched\tRating\tNote")
+		for _, e := range l.Entries() {
+			opened := ""
+			if n := len(e.Opened); n > 0 {
+				opened = fmt.Sprintf("%s (%dx)", e.Opened[n-1], n)
+			}
+			rating := ""
+			if e.Rating > 0 {
+				rating = strconv.FormatFloat(e.Rating, 'f', -1, 64)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Title, e.Director, opened, e.Watched, rating, e.Note)
+		}
+		return w.Flush()
+	}
+	return c
+}
+
+func watchlistCommand() *command {
+	c := newCommand("watchlist", "add|remove|list [<film> | <MUBI link>]",
+		"Manage films to watch, flagged when expiring or newly showing")
+	ls := addListingFlags(c.flags)
+	c.run = func(args []string, conf config) error {
+		if len(args) == 0 {
+			args = []string{"list"}
+		}
+		wl, err := watchlist.Read()
+		if err != nil {
+			return err
+		}
+		movies, err := movie.ReadFromJSON()
+		if err != nil {
+			debugging.Log().Printf("Could not read cached data: %v\n", err)
+		}
+
+		switch args[0] {
+		case "list":
+			for _, link := range wl.Links() {
+				item := wl.Films[link]
+				status := "not showing"
+				if m, ok := findByLink(link, movies); ok {
+					status = fmt.Sprintf("%d days left", m.DaysToWatch)
+				}
+				fmt.Printf("%-40s %-15s %s\n", item.Title, status, link)
+			}
+			return nil
+		case "add", "remove":
+			if len(args) < 2 {
+				return fmt.Errorf("watchlist %s requires film title, listing index or MUBI link", args[0])
+			}
+		default:
+			return fmt.Errorf("Unknown watchlist subcommand: %s", args[0])
+		}
+
+		query := strings.Join(args[1:], " ")
+		link, title := query, ""
+		if !strings.HasPrefix(query, "http") {
+			listed, err := ls.apply(movies)
+			if err != nil {
+				return err
+			}
+			m, err := pick(listed, query)
+			if err != nil {
+				return err
+			}
+			link, title = m.MubiLink, m.Title
+		}
+		if args[0] == "add" {
+			err = wl.Add(link, title, time.Now())
+		} else {
+			err = wl.Remove(link)
+		}
+		if err != nil {
+			return err
+		}
+		return wl.Write()
+	}
+	return c
+}
+
+func findByLink(link string, movies []movie.Data) (movie.Data, bool) {
+	for _, m := range movies {
+		if m.MubiLink == link {
+			return m, true
+		}
+	}
+	return movie.Data{}, false
+}
+
+func diffCommand() *command {
+	c := newCommand("diff", "", "Print films that arrived and departed between recorded lineups")
+	from := c.flags.String("from", "", "Date (YYYY-MM-DD) of older lineup, default: the one before newer lineup")
+	to := c.flags.String("to", "", "Date (YYYY-MM-DD) of newer lineup, default: most recent")
+	c.run = func(args []string, conf config) error {
+		h, err := history.Read()
+		if err != nil {
+			return err
+		}
+		d, err := h.Diff(*from, *to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Changes from %s to %s\n", d.From, d.To)
+		printFilms("Arrived", d.Arrived, h)
+		printFilms("Departed", d.Departed, h)
+		return nil
+	}
+	return c
+}
+
+func historyCommand() *command {
+	c := newCommand("history", "", "Print recorded lineups")
+	date := c.flags.String("date", "", "Print lineup recorded on date (YYYY-MM-DD)")
+	c.run = func(args []string, conf config) error {
+		h, err := history.Read()
+		if err != nil {
+			return err
+		}
+		if *date != "" {
+			snap, ok := h.Snapshot(*date)
+			if !ok {
+				return fmt.Errorf("No lineup recorded on %s", *date)
+			}
+			printFilms("Lineup on "+snap.Date, snap.Links, h)
+			return nil
+		}
+
+		for i, snap := range h.Snapshots {
+			line := fmt.Sprintf("%s  %2d films", snap.Date, len(snap.Links))
+			if i > 0 {
+				d, _ := h.Diff(h.Snapshots[i-1].Date, snap.Date)
+				line += fmt.Sprintf("  +%d -%d", len(d.Arrived), len(d.Departed))
+			}
+			fmt.Println(line)
+		}
+		return nil
+	}
+	return c
+}
+
+func statsCommand() *command {
+	c := newCommand("stats", "", "Print statistics of stored films")
+	format := c.flags.String("format", "table", "Output format: [table|json]")
+	all := c.flags.Bool("history", false, "Include all films recorded in history, ratings are available only for current films")
+	top := c.flags.Int("top", 10, "Number of most frequent values listed per distribution. Value equal or less than zero lists all")
+	c.run = func(args []string, conf config) error {
+		movies, err := movie.ReadFromJSON()
+		if err != nil {
+			return err
+		}
+		if *all {
+			h, err := history.Read()
+			if err != nil {
+				return err
+			}
+			for _, m := range h.Movies() {
+				if _, found := movie.Find(m, movies); !found {
+					movies = append(movies, m)
+				}
+			}
+		}
+
+		s := stats.Compute(movies)
+		switch *format {
+		case "table":
+			printer.PrintStats(os.Stdout, s, *top)
+			return nil
+		case "json":
+			out, err := json.MarshalIndent(s, "", " ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		default:
+			return fmt.Errorf("Undefined stats format: %s", *format)
+		}
+	}
+	return c
+}
+
+func exportCommand() *command {
+	c := newCommand("export", "", "Export stored films to a file")
+	format := c.flags.String("format", "csv", "Output format: ["+strings.Join(printer.ExportFormats, "|")+"]")
+	output := c.flags.String("o", "", "Output file, default: stdout")
+	ls := addListingFlags(c.flags)
+	c.run = func(args []string, conf config) error {
+		movies, err := movie.ReadFromJSON()
+		if err != nil {
+			return err
+		}
+		if movies, err = ls.apply(movies); err != nil {
+			return err
+		}
+
+		w := os.Stdout
+		if *output != "" {
+			if w, err = os.Create(*output); err != nil {
+				return err
+			}
+			defer w.Close()
+		}
+		return printer.Export(w, movies, *format)
+	}
+	return c
+}
+
+func feedCommand() *command {
+	c := newCommand("feed", "", "Print Atom feed of film arrivals recorded in history")
+	days := c.flags.Int("days", 30, "Include films which arrived in this many recent days")
+	leaving := c.flags.Bool("leaving", false, "Include films leaving tomorrow")
+	output := c.flags.String("o", "", "Output file, default: stdout")
+	c.run = func(args []string, conf config) error {
+		w := os.Stdout
+		if *output != "" {
+			var err error
+			if w, err = os.Create(*output); err != nil {
+				return err
+			}
+			defer w.Close()
+		}
+		return writeFeed(w, feed.Options{Region: movie.Region, Days: *days, Leaving: *leaving})
+	}
+	return c
+}
+
+// writeFeed writes Atom feed of history with ratings of stored films
+func writeFeed(w io.Writer, o feed.Options) error {
+	h, err := history.Read()
+	if err != nil {
+		return err
+	}
+	movies, err := movie.ReadFromJSON()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return feed.Write(w, h, movies, o, time.Now())
+}
+
+func configCommand() *command {
+	c := newCommand("config", "[show|selectors]", "Print effective configuration, or selector profile in use as json")
+	c.run = func(args []string, conf config) error {
+		if len(args) > 0 && args[0] == "selectors" {
+			if err := mubi.UseProfile(conf.SelectorsFile, conf.SelectorProfile); err != nil {
+				return err
+			}
+			out, err := json.MarshalIndent(map[string]mubi.Profile{
+				conf.SelectorProfile: mubi.CurrentProfile(),
+			}, "", " ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+		if len(args) > 0 && args[0] != "show" {
+			return fmt.Errorf("Unknown config subcommand: %s", args[0])
+		}
+		files, err := configFiles()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			files = []string{"none found"}
+		}
+		fmt.Printf("Config files: %s\n\n", strings.Join(files, ", "))
+		conf.show()
+		return nil
+	}
+	return c
+}
+
+func printFilms(header string, links []string, h *history.Store) {
+	fmt.Printf("%s (%d):\n", header, len(links))
+	for _, l := range links {
+		f := h.Films[l]
+		fmt.Printf("  %s (%s, %d)\n", f.Title, f.Director, f.Year)
+	}
+}
+
+func doctorCommand() *command {
+	c := newCommand("doctor", "", "Check that MUBI pages are still scraped correctly, stored data is not changed")
+	films := c.flags.Int("films", 3, "Number of film pages to check")
+	c.run = func(args []string, conf config) error {
+		if err := mubi.UseProfile(conf.SelectorsFile, conf.SelectorProfile); err != nil {
+			return err
+		}
+		fmt.Printf("Selector profile: %s\n", conf.SelectorProfile)
+
+		done := make(chan struct{})
+		defer close(done)
+		in, err := mubi.SendMoviesWithBasicData(done)
+		if err != nil {
+			return err
+		}
+		var basic []movie.Data
+		for m := range in {
+			basic = append(basic, m)
+		}
+
+		sample := make(chan movie.Data, mubi.MaxMovies)
+		for i := 0; i < *films && i < len(basic); i++ {
+			sample <- basic[i]
+		}
+		close(sample)
+		var detailed []movie.Data
+		for m := range mubi.SendMoviesDetails(done, sample) {
+			detailed = append(detailed, m)
+		}
+		// pages which could not be fetched say nothing about layout
+		if failures := fetch.Failures(); len(failures) > 0 {
+			for _, f := range failures {
+				fmt.Printf("FAIL  %v\n", f)
+			}
+			return fmt.Errorf("Could not fetch %d MUBI page(s), layout was not checked", len(failures))
+		}
+
+		checks := mubi.Validate(basic, detailed)
+		for _, check := range checks {
+			if check.OK() {
+				fmt.Printf("OK    %s\n", check.Name)
+				continue
+			}
+			fmt.Printf("FAIL  %s\n", check.Name)
+			for _, p := range check.Problems {
+				fmt.Printf("        %s\n", p)
+			}
+		}
+		for _, m := range detailed {
+			var sources []string
+			for source, fields := range m.SourceFields() {
+				sources = append(sources, fmt.Sprintf("%s %d", source, len(fields)))
+			}
+			sort.Strings(sources)
+			fmt.Printf("%s: fields from %s\n", m.Title, strings.Join(sources, ", "))
+		}
+
+		if len(mubi.Failed(checks)) == 0 {
+			return nil
+		}
+		dir, err := mubi.SaveDiagnostics(checks)
+		if err != nil {
+			return err
+		}
+		return &mubi.LayoutError{Checks: checks, Dir: dir}
+	}
+	return c
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/parser"
+	"github.com/llugin/mubi-parser/watchlog"
+)
+
+func lineup() []movie.Data {
+	return []movie.Data{
+		{Title: "Stalker", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1979, DaysToWatch: 3,
+			Genres: movie.List{"Drama", "Sci-Fi"}, MubiLink: "https://mubi.com/films/stalker"},
+		{Title: "Mirror", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1975, DaysToWatch: 20,
+			Genres: movie.List{"Drama"}, MubiLink: "https://mubi.com/films/mirror"},
+		{Title: "Alien", Directors: movie.List{"Ridley Scott"}, Year: 1979, DaysToWatch: 10,
+			Genres: movie.List{"Horror", "Sci-Fi"}, MubiLink: "https://mubi.com/films/alien"},
+		{Title: "Solaris", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1972, DaysToWatch: 1,
+			Genres: movie.List{"Drama", "Sci-Fi"}, MubiLink: "https://mubi.com/films/solaris"},
+	}
+}
+
+func TestListingIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	watchlog.JSONPath = dir
+	l, err := watchlog.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.MarkWatched(lineup()[3], time.Now(), 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args  []string
+		index string
+		want  string
+	}{
+		{nil, "1", "Mirror"},
+		{nil, "4", "Solaris"},
+		{[]string{"-sort", "year-"}, "1", "Solaris"},
+		{[]string{"-unwatched"}, "3", "Stalker"},
+		{[]string{"-unwatched", "-sort", "year-"}, "1", "Mirror"},
+		{[]string{"-genre", "sci-fi"}, "2", "Stalker"},
+		{[]string{"-unwatched", "-genre", "drama", "-director", "andrei tarkovsky"}, "2", "Stalker"},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		ls := addListingFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		listed, err := ls.apply(lineup())
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := pick(listed, tt.index)
+		if err != nil {
+			t.Errorf("%v %s: %v", tt.args, tt.index, err)
+			continue
+		}
+		if m.Title != tt.want {
+			t.Errorf("%v %s: picked %s, want %s", tt.args, tt.index, m.Title, tt.want)
+		}
+	}
+}
+
+func TestRegionLineups(t *testing.T) {
+	defer func(current []string) { regions = current }(regions)
+	regions = []string{"GB", "US", "DE"}
+	movie.Region = "GB"
+
+	lineups, err := regionLineups(func() ([]movie.Data, error) {
+		movies := []movie.Data{{Title: "Stalker", Region: movie.Region}}
+		if movie.Region == "DE" {
+			return movies, nil
+		}
+		return movies, &parser.EnrichmentError{Failed: movies}
+	})
+	if len(lineups) != 3 || lineups["US"][0].Region != "US" {
+		t.Errorf("lineups %+v, want one of each region", lineups)
+	}
+	if !enrichmentFailed(err) {
+		t.Fatalf("error %v, want enrichment failure", err)
+	}
+	for _, r := range []string{"Region GB", "Region US"} {
+		if !strings.Contains(err.Error(), r) {
+			t.Errorf("%q not in %q", r, err)
+		}
+	}
+	if movie.Region != "GB" {
+		t.Errorf("region %s not restored", movie.Region)
+	}
+
+	_, err = regionLineups(func() ([]movie.Data, error) {
+		return nil, errors.New("503 Service Unavailable")
+	})
+	if err == nil || enrichmentFailed(err) || !strings.Contains(err.Error(), "Region GB") {
+		t.Errorf("error %v, want failure of the first region", err)
+	}
+}
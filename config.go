@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/mubi"
+)
+
+const (
+	appName        = "mubi-parser"
+	legacyConfName = "mubiconf.json"
+	legacyDataFile = "mubi.json"
+
+	// config value sources, from the lowest priority
+	sourceDefault = "default"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// config holds settings read from config files, environment variables and
+// flags. Each field is identified in files by its json/toml key and can be
+// overridden by env variable from its env tag
+type config struct {
+	OMDBKey  string `json:"OMDBKey" toml:"OMDBKey" env:"MUBI_OMDB_KEY"`
+	DataPath string `json:"DataPath" toml:"DataPath" env:"MUBI_DATA_PATH"`
+	LogPath  string `json:"LogPath" toml:"LogPath" env:"MUBI_LOG_PATH"`
+
+	// WatchlistDays - watchlisted films with this many days left are flagged
+	WatchlistDays int `json:"WatchlistDays" toml:"WatchlistDays" env:"MUBI_WATCHLIST_DAYS"`
+
+	// Score preferences, maps of value to bonus points. Decades are
+	// given like "1970s"
+	ScoreGenres    map[string]float64 `json:"ScoreGenres" toml:"ScoreGenres" env:"MUBI_SCORE_GENRES"`
+	ScoreCountries map[string]float64 `json:"ScoreCountries" toml:"ScoreCountries" env:"MUBI_SCORE_COUNTRIES"`
+	ScoreDecades   map[string]float64 `json:"ScoreDecades" toml:"ScoreDecades" env:"MUBI_SCORE_DECADES"`
+	ScoreMaxMins   int                `json:"ScoreMaxMins" toml:"ScoreMaxMins" env:"MUBI_SCORE_MAX_MINS"`
+	ScoreUrgency   float64            `json:"ScoreUrgency" toml:"ScoreUrgency" env:"MUBI_SCORE_URGENCY"`
+
+	// Fetching: workers of each stage and requests per second to each
+	// host, shared by the workers. RateJitter adds random delay up to this
+	// fraction of interval between requests
+	MubiWorkers int     `json:"MubiWorkers" toml:"MubiWorkers" env:"MUBI_MUBI_WORKERS"`
+	MubiRate    float64 `json:"MubiRate" toml:"MubiRate" env:"MUBI_MUBI_RATE"`
+	OMDBWorkers int     `json:"OMDBWorkers" toml:"OMDBWorkers" env:"MUBI_OMDB_WORKERS"`
+	OMDBRate    float64 `json:"OMDBRate" toml:"OMDBRate" env:"MUBI_OMDB_RATE"`
+	RateJitter  float64 `json:"RateJitter" toml:"RateJitter" env:"MUBI_RATE_JITTER"`
+
+	// FetchRetries - retries of requests failed with network errors or
+	// transient statuses like 429 and 503. FetchTimeout - seconds a single
+	// request may take
+	FetchRetries int     `json:"FetchRetries" toml:"FetchRetries" env:"MUBI_FETCH_RETRIES"`
+	FetchTimeout float64 `json:"FetchTimeout" toml:"FetchTimeout" env:"MUBI_FETCH_TIMEOUT"`
+
+	// RefreshDays maps fields, named as in data files, to days after which
+	// stored films have them fetched again. Fields not listed or with zero
+	// days are fetched once
+	RefreshDays map[string]float64 `json:"RefreshDays" toml:"RefreshDays" env:"MUBI_REFRESH_DAYS"`
+
+	// Stored lineup is fetched again after MUBI changes it each day at
+	// RolloverTime ("15:04") in RolloverZone, IANA timezone which defaults
+	// to the one of the region, or when it is older than CacheMaxHours
+	// (0 for no limit)
+	RolloverTime  string  `json:"RolloverTime" toml:"RolloverTime" env:"MUBI_ROLLOVER_TIME"`
+	RolloverZone  string  `json:"RolloverZone" toml:"RolloverZone" env:"MUBI_ROLLOVER_ZONE"`
+	CacheMaxHours float64 `json:"CacheMaxHours" toml:"CacheMaxHours" env:"MUBI_CACHE_MAX_HOURS"`
+
+	// SelectorsFile holds selector profiles, SelectorProfile names the one
+	// used for scraping MUBI pages
+	SelectorsFile   string `json:"SelectorsFile" toml:"SelectorsFile" env:"MUBI_SELECTORS_FILE"`
+	SelectorProfile string `json:"SelectorProfile" toml:"SelectorProfile" env:"MUBI_SELECTOR_PROFILE"`
+
+	// sources maps config keys to where their values came from
+	sources map[string]string
+}
+
+// configFlags are command line overrides of config values
+type configFlags struct {
+	omdbKey  string
+	dataPath string
+	logPath  string
+	set      setFlags
+}
+
+// setFlags collects repeated '-set Key=value' flags
+type setFlags []string
+
+func (s *setFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *setFlags) Set(val string) error {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("Expected Key=value, got '%s'", val)
+	}
+	*s = append(*s, val)
+	return nil
+}
+
+func (f *configFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.omdbKey, "omdb-key", "", "OMDB API key, overrides config")
+	fs.StringVar(&f.dataPath, "data-path", "", "Directory for data files, overrides config")
+	fs.StringVar(&f.logPath, "log-path", "", "Directory for debug log, overrides config")
+	fs.Var(&f.set, "set", "Override any config value, as Key=value. Can be repeated")
+}
+
+// readConfig merges config values in order of priority:
+// flags > MUBI_* env variables > $XDG_CONFIG_HOME/mubi-parser/config.{json,toml}
+// > mubiconf.json in executable directory > defaults
+func readConfig(flags configFlags) (config, error) {
+	c, err := defaultConfig()
+	if err != nil {
+		return c, err
+	}
+
+	files, err := configFiles()
+	if err != nil {
+		return c, err
+	}
+	for _, path := range files {
+		if err := c.readFile(path); err != nil {
+			return c, err
+		}
+	}
+
+	for _, key := range configKeys() {
+		if val := os.Getenv(envName(key)); val != "" {
+			if err := c.set(key, val, sourceEnv+" "+envName(key)); err != nil {
+				return c, err
+			}
+		}
+	}
+
+	overrides := map[string]string{
+		"OMDBKey":  flags.omdbKey,
+		"DataPath": flags.dataPath,
+		"LogPath":  flags.logPath,
+	}
+	for _, kv := range flags.set {
+		parts := strings.SplitN(kv, "=", 2)
+		overrides[parts[0]] = parts[1]
+	}
+	for key, val := range overrides {
+		if val == "" {
+			continue
+		}
+		if err := c.set(key, val, sourceFlag); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+func defaultConfig() (config, error) {
+	c := config{sources: map[string]string{}}
+	ex, err := os.Executable()
+	if err != nil {
+		return c, err
+	}
+	exDir := filepath.Dir(ex)
+
+	// Data kept next to executable by older versions stays there
+	dataDir := exDir
+	if _, err := os.Stat(filepath.Join(exDir, legacyDataFile)); os.IsNotExist(err) {
+		dataDir, err = xdgDir("XDG_DATA_HOME", ".local/share")
+		if err != nil {
+			return c, err
+		}
+	}
+	c.DataPath = dataDir
+	c.LogPath = dataDir
+
+	confDir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return c, err
+	}
+	c.SelectorsFile = filepath.Join(confDir, "selectors.json")
+	c.SelectorProfile = mubi.DefaultProfileName
+	c.WatchlistDays = 3
+	c.MubiWorkers = 4
+	c.MubiRate = 1
+	c.OMDBWorkers = 4
+	c.OMDBRate = 5
+	c.RateJitter = 0.2
+	c.FetchRetries = 3
+	c.FetchTimeout = 30
+	c.RolloverTime = "00:00"
+	c.RefreshDays = map[string]float64{
+		"MUBI rating": 2, "MUBI ratings num": 2,
+		"IMDB rating": 7, "IMDB ratings num": 7,
+	}
+	c.ScoreUrgency = 0.5
+	for _, key := range configKeys() {
+		c.sources[key] = sourceDefault
+	}
+	return c, nil
+}
+
+// configFiles returns existing config files from the lowest priority
+func configFiles() ([]string, error) {
+	var files []string
+	ex, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if path := filepath.Join(filepath.Dir(ex), legacyConfName); exists(path) {
+		files = append(files, path)
+	}
+
+	dir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return nil, err
+	}
+	jsonPath := filepath.Join(dir, "config.json")
+	tomlPath := filepath.Join(dir, "config.toml")
+	switch {
+	case exists(jsonPath) && exists(tomlPath):
+		return nil, fmt.Errorf("Both %s and %s exist, remove one of them", jsonPath, tomlPath)
+	case exists(jsonPath):
+		files = append(files, jsonPath)
+	case exists(tomlPath):
+		files = append(files, tomlPath)
+	}
+	return files, nil
+}
+
+// xdgDir returns application directory in XDG base directory given by env,
+// falling back to fallback relative to home directory
+func xdgDir(env, fallback string) (string, error) {
+	base := os.Getenv(env)
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, fallback)
+	}
+	return filepath.Join(base, appName), nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readFile overrides config values with those found in json or toml file
+func (c *config) readFile(path string) error {
+	out, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	if filepath.Ext(path) == ".toml" {
+		md, err := toml.Decode(string(out), c)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return unknownKeyError(path, undecoded[0].String())
+		}
+		for _, k := range md.Keys() {
+			keys = append(keys, k.String())
+		}
+	} else {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(out, &raw); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(out))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			for k := range raw {
+				if _, ok := c.field(k); !ok {
+					return unknownKeyError(path, k)
+				}
+			}
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		for k := range raw {
+			keys = append(keys, k)
+		}
+	}
+
+	for _, k := range keys {
+		c.sources[k] = path
+	}
+	return nil
+}
+
+func unknownKeyError(path, key string) error {
+	msg := fmt.Sprintf("%s: unknown config key '%s'", path, key)
+	for _, known := range configKeys() {
+		if strings.HasPrefix(strings.ToLower(known), strings.ToLower(key)) {
+			msg += fmt.Sprintf(", did you mean '%s'?", known)
+			break
+		}
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// configKeys returns keys of all config values
+func configKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(config{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := fieldKey(t.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// fieldKey returns key identifying config field in files, empty for
+// unexported fields
+func fieldKey(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+// envName returns name of env variable overriding config key
+func envName(key string) string {
+	t := reflect.TypeOf(config{})
+	for i := 0; i < t.NumField(); i++ {
+		if fieldKey(t.Field(i)) == key {
+			return t.Field(i).Tag.Get("env")
+		}
+	}
+	return ""
+}
+
+// field returns settable config field identified by key
+func (c *config) field(key string) (reflect.Value, bool) {
+	if key == "" {
+		return reflect.Value{}, false
+	}
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if fieldKey(v.Type().Field(i)) == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// set parses string value into config field identified by key
+func (c *config) set(key, val, source string) error {
+	if key == "" {
+		return fmt.Errorf("%s: empty config key", source)
+	}
+	f, ok := c.field(key)
+	if !ok {
+		return unknownKeyError(source, key)
+	}
+	if val == "" {
+		return fmt.Errorf("%s: %s: empty value", source, key)
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(val)
+	case reflect.Int:
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", source, key, err)
+		}
+		f.SetInt(int64(i))
+	case reflect.Float64:
+		fl, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", source, key, err)
+		}
+		f.SetFloat(fl)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", source, key, err)
+		}
+		f.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, s := range strings.Split(val, ",") {
+			items = append(items, strings.TrimSpace(s))
+		}
+		f.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		// given as "key:value,key:value"
+		items := map[string]float64{}
+		for _, s := range strings.Split(val, ",") {
+			kv := strings.SplitN(s, ":", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+				return fmt.Errorf("%s: %s: expected key:value, got '%s'", source, key, s)
+			}
+			fl, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %v", source, key, err)
+			}
+			items[strings.TrimSpace(kv[0])] = fl
+		}
+		f.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%s: %s: unsupported config value type", source, key)
+	}
+	c.sources[key] = source
+	return nil
+}
+
+// show prints effective config values with their sources
+func (c *config) show() {
+	keys := configKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		f, _ := c.field(key)
+		val := fmt.Sprint(f.Interface())
+		if key == "OMDBKey" && len(val) > 2 {
+			val = val[:2] + strings.Repeat("*", len(val)-2)
+		}
+		fmt.Printf("%-15s = %-30s (%s)\n", key, val, c.sources[key])
+	}
+}
+
+// refreshAfter converts RefreshDays to max ages of fields, leaving out
+// fields with zero days
+func (c *config) refreshAfter() (map[string]time.Duration, error) {
+	after := map[string]time.Duration{}
+	for field, days := range c.RefreshDays {
+		if !movie.IsField(field) {
+			return nil, fmt.Errorf("RefreshDays: unknown field '%s'", field)
+		}
+		if days < 0 {
+			return nil, fmt.Errorf("RefreshDays: negative days of '%s'", field)
+		}
+		if days > 0 {
+			after[field] = time.Duration(days * 24 * float64(time.Hour))
+		}
+	}
+	return after, nil
+}
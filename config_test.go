@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigSet(t *testing.T) {
+	tests := []struct {
+		key, val string
+		want     interface{}
+	}{
+		{"OMDBKey", "abc", "abc"},
+		{"WatchlistDays", "5", 5},
+		{"MubiRate", "0.5", 0.5},
+		{"ScoreGenres", "Drama:1, Horror:-1", map[string]float64{"Drama": 1, "Horror": -1}},
+	}
+	for _, tt := range tests {
+		c := config{sources: map[string]string{}}
+		if err := c.set(tt.key, tt.val, sourceFlag); err != nil {
+			t.Errorf("set(%q, %q): %v", tt.key, tt.val, err)
+			continue
+		}
+		f, _ := c.field(tt.key)
+		if got := f.Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("set(%q, %q) = %v, want %v", tt.key, tt.val, got, tt.want)
+		}
+		if c.sources[tt.key] != sourceFlag {
+			t.Errorf("set(%q, %q): source %q, want %q", tt.key, tt.val, c.sources[tt.key], sourceFlag)
+		}
+	}
+}
+
+func TestConfigSetRejects(t *testing.T) {
+	tests := []struct {
+		key, val string
+	}{
+		{"", "a:1"},
+		{"sources", "a:1"},
+		{"NoSuchKey", "1"},
+		{"OMDBKey", ""},
+		{"WatchlistDays", "three"},
+		{"ScoreGenres", "Drama"},
+		{"ScoreGenres", ":1"},
+	}
+	for _, tt := range tests {
+		c := config{sources: map[string]string{}}
+		if err := c.set(tt.key, tt.val, sourceFlag); err == nil {
+			t.Errorf("set(%q, %q) succeeded, want error", tt.key, tt.val)
+		}
+	}
+}
+
+func TestSetFlags(t *testing.T) {
+	for _, val := range []string{"Key=value", "Key=a=b"} {
+		var s setFlags
+		if err := s.Set(val); err != nil {
+			t.Errorf("Set(%q): %v", val, err)
+		}
+	}
+	for _, val := range []string{"", "Key", "=a:1", "Key=", "="} {
+		var s setFlags
+		if err := s.Set(val); err == nil {
+			t.Errorf("Set(%q) succeeded, want error", val)
+		}
+	}
+}
+
+func TestRefreshAfter(t *testing.T) {
+	c := config{RefreshDays: map[string]float64{"MUBI rating": 2, "IMDB rating": 0.5, "synopsis": 0}}
+	got, err := c.refreshAfter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"MUBI rating": 48 * time.Hour, "IMDB rating": 12 * time.Hour}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("refreshAfter() = %v, want %v", got, want)
+	}
+
+	for _, days := range []map[string]float64{{"rating": 2}, {"MUBI rating": -1}} {
+		c := config{RefreshDays: days}
+		if got, err := c.refreshAfter(); err == nil {
+			t.Errorf("refreshAfter() of %v = %v, want error", days, got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package country
+
+import (
+	"strings"
+)
+
+// Country is an ISO 3166 country with its display name
+type Country struct {
+	Name    string
+	Alpha2  string
+	Alpha3  string
+	Aliases []string
+	// Historical countries no longer exist, like Soviet Union
+	Historical bool
+}
+
+// display styles of countries
+const (
+	StyleName = "name"
+	StyleCode = "code"
+	StyleFlag = "flag"
+)
+
+// Styles lists available display styles
+var Styles = []string{StyleName, StyleCode, StyleFlag}
+
+// Lookup finds country by its name, alias, alpha-2 or alpha-3 code,
+// ignoring case. Current countries take precedence over historical ones
+// with the same alpha-2 code
+func Lookup(s string) (Country, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Country{}, false
+	}
+	for _, c := range iso {
+		if strings.EqualFold(c.Name, s) || strings.EqualFold(c.Alpha3, s) {
+			return c, true
+		}
+		if !c.Historical && strings.EqualFold(c.Alpha2, s) {
+			return c, true
+		}
+		for _, a := range c.Aliases {
+			if strings.EqualFold(a, s) {
+				return c, true
+			}
+		}
+	}
+	// formerly used alpha-2 codes not reassigned to current countries
+	for _, c := range iso {
+		if c.Historical && strings.EqualFold(c.Alpha2, s) {
+			return c, true
+		}
+	}
+	return Country{}, false
+}
+
+// Match checks if query names the same country as name, by any of its
+// names or codes
+func Match(query, name string) bool {
+	if strings.EqualFold(query, name) {
+		return true
+	}
+	q, ok := Lookup(query)
+	if !ok {
+		return false
+	}
+	c, ok := Lookup(name)
+	return ok && q.Alpha3 == c.Alpha3
+}
+
+// Flag returns flag emoji of country. Historical countries have no flags,
+// their alpha-3 code is returned instead
+func (c Country) Flag() string {
+	if c.Historical || len(c.Alpha2) != 2 {
+		return c.Alpha3
+	}
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(c.Alpha2) {
+		// regional indicator symbols start at U+1F1E6 for 'A'
+		sb.WriteRune(0x1F1E6 + r - 'A')
+	}
+	return sb.String()
+}
+
+// Format returns name in given display style. Unknown countries are
+// returned unchanged
+func Format(name, style string) string {
+	c, ok := Lookup(name)
+	if !ok {
+		return name
+	}
+	switch style {
+	case StyleCode:
+		return c.Alpha3
+	case StyleFlag:
+		return c.Flag()
+	default:
+		return c.Name
+	}
+}
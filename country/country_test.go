@@ -0,0 +1,95 @@
+package country
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		in     string
+		alpha3 string
+	}{
+		{"France", "FRA"},
+		{"france", "FRA"},
+		{" FR ", "FRA"},
+		{"fra", "FRA"},
+		{"UK", "GBR"},
+		{"Great Britain", "GBR"},
+		{"USA", "USA"},
+		{"West Germany", "DEU"},
+		{"Czechia", "CZE"},
+		{"Soviet Union", "SUN"},
+		{"USSR", "SUN"},
+		{"SU", "SUN"},
+		{"East Germany", "DDR"},
+		{"DD", "DDR"},
+		{"Yugoslavia", "YUG"},
+		// CS was Czechoslovakia before Serbia and Montenegro
+		{"CS", "CSK"},
+		{"SCG", "SCG"},
+		{"Republic of Korea", "KOR"},
+		{"Islamic Republic of Iran", "IRN"},
+		{"Caribbean Netherlands", "BES"},
+		{"Côte d'Ivoire", "CIV"},
+		{"Ivory Coast", "CIV"},
+	}
+	for _, tt := range tests {
+		c, ok := Lookup(tt.in)
+		if !ok || c.Alpha3 != tt.alpha3 {
+			t.Errorf("Lookup(%q) = %v, %v, want %s", tt.in, c.Alpha3, ok, tt.alpha3)
+		}
+	}
+	for _, s := range []string{"", "Atlantis", "XX"} {
+		if c, ok := Lookup(s); ok {
+			t.Errorf("Lookup(%q) = %v, want not found", s, c.Name)
+		}
+	}
+}
+
+// names are read from comma separated lists, so they must hold no commas
+func TestNamesHaveNoCommas(t *testing.T) {
+	for _, c := range iso {
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			if strings.Contains(name, ",") {
+				t.Errorf("%s: name %q has a comma", c.Alpha3, name)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query, name string
+		want        bool
+	}{
+		{"fr", "France", true},
+		{"SUN", "Soviet Union", true},
+		{"Russia", "Soviet Union", false},
+		{"Holland", "Netherlands", true},
+		{"Narnia", "Narnia", true},
+		{"Narnia", "France", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.query, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.query, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name, style, want string
+	}{
+		{"uk", StyleName, "United Kingdom"},
+		{"United Kingdom", StyleCode, "GBR"},
+		{"France", StyleFlag, "🇫🇷"},
+		{"Soviet Union", StyleFlag, "SUN"},
+		{"Narnia", StyleCode, "Narnia"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.name, tt.style); got != tt.want {
+			t.Errorf("Format(%q, %q) = %q, want %q", tt.name, tt.style, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package country
+
+// iso is a table of ISO 3166-1 countries followed by ISO 3166-3 formerly
+// used ones. Formerly used alpha-2 codes may collide with each other,
+// like CS of Czechoslovakia and Serbia and Montenegro. Names and aliases
+// hold no commas, which separate countries of a film
+var iso = []Country{
+	{"Afghanistan", "AF", "AFG", nil, false},
+	{"Åland Islands", "AX", "ALA", []string{"Aland Islands"}, false},
+	{"Albania", "AL", "ALB", nil, false},
+	{"Algeria", "DZ", "DZA", nil, false},
+	{"American Samoa", "AS", "ASM", nil, false},
+	{"Andorra", "AD", "AND", nil, false},
+	{"Angola", "AO", "AGO", nil, false},
+	{"Anguilla", "AI", "AIA", nil, false},
+	{"Antarctica", "AQ", "ATA", nil, false},
+	{"Antigua and Barbuda", "AG", "ATG", nil, false},
+	{"Argentina", "AR", "ARG", nil, false},
+	{"Armenia", "AM", "ARM", nil, false},
+	{"Aruba", "AW", "ABW", nil, false},
+	{"Australia", "AU", "AUS", nil, false},
+	{"Austria", "AT", "AUT", nil, false},
+	{"Azerbaijan", "AZ", "AZE", nil, false},
+	{"Bahamas", "BS", "BHS", []string{"The Bahamas"}, false},
+	{"Bahrain", "BH", "BHR", nil, false},
+	{"Bangladesh", "BD", "BGD", nil, false},
+	{"Barbados", "BB", "BRB", nil, false},
+	{"Belarus", "BY", "BLR", nil, false},
+	{"Belgium", "BE", "BEL", nil, false},
+	{"Belize", "BZ", "BLZ", nil, false},
+	{"Benin", "BJ", "BEN", nil, false},
+	{"Bermuda", "BM", "BMU", nil, false},
+	{"Bhutan", "BT", "BTN", nil, false},
+	{"Bolivia", "BO", "BOL", []string{"Plurinational State of Bolivia"}, false},
+	{"Caribbean Netherlands", "BQ", "BES", []string{"Bonaire", "Bonaire Sint Eustatius and Saba"}, false},
+	{"Bosnia and Herzegovina", "BA", "BIH", []string{"Bosnia-Herzegovina"}, false},
+	{"Botswana", "BW", "BWA", nil, false},
+	{"Bouvet Island", "BV", "BVT", nil, false},
+	{"Brazil", "BR", "BRA", nil, false},
+	{"British Indian Ocean Territory", "IO", "IOT", nil, false},
+	{"Brunei", "BN", "BRN", []string{"Brunei Darussalam"}, false},
+	{"Bulgaria", "BG", "BGR", nil, false},
+	{"Burkina Faso", "BF", "BFA", nil, false},
+	{"Burundi", "BI", "BDI", nil, false},
+	{"Cape Verde", "CV", "CPV", []string{"Cabo Verde"}, false},
+	{"Cambodia", "KH", "KHM", nil, false},
+	{"Cameroon", "CM", "CMR", nil, false},
+	{"Canada", "CA", "CAN", nil, false},
+	{"Cayman Islands", "KY", "CYM", nil, false},
+	{"Central African Republic", "CF", "CAF", nil, false},
+	{"Chad", "TD", "TCD", nil, false},
+	{"Chile", "CL", "CHL", nil, false},
+	{"China", "CN", "CHN", []string{"People's Republic of China"}, false},
+	{"Christmas Island", "CX", "CXR", nil, false},
+	{"Cocos (Keeling) Islands", "CC", "CCK", nil, false},
+	{"Colombia", "CO", "COL", nil, false},
+	{"Comoros", "KM", "COM", nil, false},
+	{"Congo", "CG", "COG", []string{"Republic of the Congo", "Congo-Brazzaville"}, false},
+	{"DR Congo", "CD", "COD", []string{"Democratic Republic of the Congo", "Zaire"}, false},
+	{"Cook Islands", "CK", "COK", nil, false},
+	{"Costa Rica", "CR", "CRI", nil, false},
+	{"Côte d'Ivoire", "CI", "CIV", []string{"Ivory Coast", "Cote d'Ivoire"}, false},
+	{"Croatia", "HR", "HRV", nil, false},
+	{"Cuba", "CU", "CUB", nil, false},
+	{"Curaçao", "CW", "CUW", []string{"Curacao"}, false},
+	{"Cyprus", "CY", "CYP", nil, false},
+	{"Czech Republic", "CZ", "CZE", []string{"Czechia"}, false},
+	{"Denmark", "DK", "DNK", nil, false},
+	{"Djibouti", "DJ", "DJI", nil, false},
+	{"Dominica", "DM", "DMA", nil, false},
+	{"Dominican Republic", "DO", "DOM", nil, false},
+	{"Ecuador", "EC", "ECU", nil, false},
+	{"Egypt", "EG", "EGY", nil, false},
+	{"El Salvador", "SV", "SLV", nil, false},
+	{"Equatorial Guinea", "GQ", "GNQ", nil, false},
+	{"Eritrea", "ER", "ERI", nil, false},
+	{"Estonia", "EE", "EST", nil, false},
+	{"Eswatini", "SZ", "SWZ", []string{"Swaziland"}, false},
+	{"Ethiopia", "ET", "ETH", nil, false},
+	{"Falkland Islands", "FK", "FLK", []string{"Falkland Islands (Malvinas)"}, false},
+	{"Faroe Islands", "FO", "FRO", nil, false},
+	{"Fiji", "FJ", "FJI", nil, false},
+	{"Finland", "FI", "FIN", nil, false},
+	{"France", "FR", "FRA", nil, false},
+	{"French Guiana", "GF", "GUF", nil, false},
+	{"French Polynesia", "PF", "PYF", nil, false},
+	{"French Southern Territories", "TF", "ATF", nil, false},
+	{"Gabon", "GA", "GAB", nil, false},
+	{"Gambia", "GM", "GMB", []string{"The Gambia"}, false},
+	{"Georgia", "GE", "GEO", nil, false},
+	{"Germany", "DE", "DEU", []string{"West Germany", "Federal Republic of Germany"}, false},
+	{"Ghana", "GH", "GHA", nil, false},
+	{"Gibraltar", "GI", "GIB", nil, false},
+	{"Greece", "GR", "GRC", nil, false},
+	{"Greenland", "GL", "GRL", nil, false},
+	{"Grenada", "GD", "GRD", nil, false},
+	{"Guadeloupe", "GP", "GLP", nil, false},
+	{"Guam", "GU", "GUM", nil, false},
+	{"Guatemala", "GT", "GTM", nil, false},
+	{"Guernsey", "GG", "GGY", nil, false},
+	{"Guinea", "GN", "GIN", nil, false},
+	{"Guinea-Bissau", "GW", "GNB", nil, false},
+	{"Guyana", "GY", "GUY", nil, false},
+	{"Haiti", "HT", "HTI", nil, false},
+	{"Heard Island and McDonald Islands", "HM", "HMD", nil, false},
+	{"Vatican City", "VA", "VAT", []string{"Holy See"}, false},
+	{"Honduras", "HN", "HND", nil, false},
+	{"Hong Kong", "HK", "HKG", nil, false},
+	{"Hungary", "HU", "HUN", nil, false},
+	{"Iceland", "IS", "ISL", nil, false},
+	{"India", "IN", "IND", nil, false},
+	{"Indonesia", "ID", "IDN", nil, false},
+	{"Iran", "IR", "IRN", []string{"Islamic Republic of Iran"}, false},
+	{"Iraq", "IQ", "IRQ", nil, false},
+	{"Ireland", "IE", "IRL", []string{"Republic of Ireland"}, false},
+	{"Isle of Man", "IM", "IMN", nil, false},
+	{"Israel", "IL", "ISR", nil, false},
+	{"Italy", "IT", "ITA", nil, false},
+	{"Jamaica", "JM", "JAM", nil, false},
+	{"Japan", "JP", "JPN", nil, false},
+	{"Jersey", "JE", "JEY", nil, false},
+	{"Jordan", "JO", "JOR", nil, false},
+	{"Kazakhstan", "KZ", "KAZ", nil, false},
+	{"Kenya", "KE", "KEN", nil, false},
+	{"Kiribati", "KI", "KIR", nil, false},
+	{"North Korea", "KP", "PRK", []string{"Democratic People's Republic of Korea"}, false},
+	{"South Korea", "KR", "KOR", []string{"Republic of Korea", "Korea"}, false},
+	{"Kosovo", "XK", "XKX", nil, false},
+	{"Kuwait", "KW", "KWT", nil, false},
+	{"Kyrgyzstan", "KG", "KGZ", nil, false},
+	{"Laos", "LA", "LAO", []string{"Lao People's Democratic Republic"}, false},
+	{"Latvia", "LV", "LVA", nil, false},
+	{"Lebanon", "LB", "LBN", nil, false},
+	{"Lesotho", "LS", "LSO", nil, false},
+	{"Liberia", "LR", "LBR", nil, false},
+	{"Libya", "LY", "LBY", nil, false},
+	{"Liechtenstein", "LI", "LIE", nil, false},
+	{"Lithuania", "LT", "LTU", nil, false},
+	{"Luxembourg", "LU", "LUX", nil, false},
+	{"Macao", "MO", "MAC", []string{"Macau"}, false},
+	{"Madagascar", "MG", "MDG", nil, false},
+	{"Malawi", "MW", "MWI", nil, false},
+	{"Malaysia", "MY", "MYS", nil, false},
+	{"Maldives", "MV", "MDV", nil, false},
+	{"Mali", "ML", "MLI", nil, false},
+	{"Malta", "MT", "MLT", nil, false},
+	{"Marshall Islands", "MH", "MHL", nil, false},
+	{"Martinique", "MQ", "MTQ", nil, false},
+	{"Mauritania", "MR", "MRT", nil, false},
+	{"Mauritius", "MU", "MUS", nil, false},
+	{"Mayotte", "YT", "MYT", nil, false},
+	{"Mexico", "MX", "MEX", nil, false},
+	{"Micronesia", "FM", "FSM", []string{"Federated States of Micronesia"}, false},
+	{"Moldova", "MD", "MDA", []string{"Republic of Moldova"}, false},
+	{"Monaco", "MC", "MCO", nil, false},
+	{"Mongolia", "MN", "MNG", nil, false},
+	{"Montenegro", "ME", "MNE", nil, false},
+	{"Montserrat", "MS", "MSR", nil, false},
+	{"Morocco", "MA", "MAR", nil, false},
+	{"Mozambique", "MZ", "MOZ", nil, false},
+	{"Myanmar", "MM", "MMR", []string{"Burma"}, false},
+	{"Namibia", "NA", "NAM", nil, false},
+	{"Nauru", "NR", "NRU", nil, false},
+	{"Nepal", "NP", "NPL", nil, false},
+	{"Netherlands", "NL", "NLD", []string{"The Netherlands", "Holland"}, false},
+	{"New Caledonia", "NC", "NCL", nil, false},
+	{"New Zealand", "NZ", "NZL", nil, false},
+	{"Nicaragua", "NI", "NIC", nil, false},
+	{"Niger", "NE", "NER", nil, false},
+	{"Nigeria", "NG", "NGA", nil, false},
+	{"Niue", "NU", "NIU", nil, false},
+	{"Norfolk Island", "NF", "NFK", nil, false},
+	{"North Macedonia", "MK", "MKD", []string{"Macedonia", "Republic of North Macedonia"}, false},
+	{"Northern Mariana Islands", "MP", "MNP", nil, false},
+	{"Norway", "NO", "NOR", nil, false},
+	{"Oman", "OM", "OMN", nil, false},
+	{"Pakistan", "PK", "PAK", nil, false},
+	{"Palau", "PW", "PLW", nil, false},
+	{"Palestine", "PS", "PSE", []string{"State of Palestine", "Occupied Palestinian Territory"}, false},
+	{"Panama", "PA", "PAN", nil, false},
+	{"Papua New Guinea", "PG", "PNG", nil, false},
+	{"Paraguay", "PY", "PRY", nil, false},
+	{"Peru", "PE", "PER", nil, false},
+	{"Philippines", "PH", "PHL", nil, false},
+	{"Pitcairn", "PN", "PCN", nil, false},
+	{"Poland", "PL", "POL", nil, false},
+	{"Portugal", "PT", "PRT", nil, false},
+	{"Puerto Rico", "PR", "PRI", nil, false},
+	{"Qatar", "QA", "QAT", nil, false},
+	{"Réunion", "RE", "REU", []string{"Reunion"}, false},
+	{"Romania", "RO", "ROU", nil, false},
+	{"Russia", "RU", "RUS", []string{"Russian Federation"}, false},
+	{"Rwanda", "RW", "RWA", nil, false},
+	{"Saint Barthélemy", "BL", "BLM", nil, false},
+	{"Saint Helena", "SH", "SHN", nil, false},
+	{"Saint Kitts and Nevis", "KN", "KNA", nil, false},
+	{"Saint Lucia", "LC", "LCA", nil, false},
+	{"Saint Martin", "MF", "MAF", nil, false},
+	{"Saint Pierre and Miquelon", "PM", "SPM", nil, false},
+	{"Saint Vincent and the Grenadines", "VC", "VCT", nil, false},
+	{"Samoa", "WS", "WSM", nil, false},
+	{"San Marino", "SM", "SMR", nil, false},
+	{"São Tomé and Príncipe", "ST", "STP", []string{"Sao Tome and Principe"}, false},
+	{"Saudi Arabia", "SA", "SAU", nil, false},
+	{"Senegal", "SN", "SEN", nil, false},
+	{"Serbia", "RS", "SRB", nil, false},
+	{"Seychelles", "SC", "SYC", nil, false},
+	{"Sierra Leone", "SL", "SLE", nil, false},
+	{"Singapore", "SG", "SGP", nil, false},
+	{"Sint Maarten", "SX", "SXM", nil, false},
+	{"Slovakia", "SK", "SVK", nil, false},
+	{"Slovenia", "SI", "SVN", nil, false},
+	{"Solomon Islands", "SB", "SLB", nil, false},
+	{"Somalia", "SO", "SOM", nil, false},
+	{"South Africa", "ZA", "ZAF", []string{"RSA"}, false},
+	{"South Georgia and the South Sandwich Islands", "GS", "SGS", nil, false},
+	{"South Sudan", "SS", "SSD", nil, false},
+	{"Spain", "ES", "ESP", nil, false},
+	{"Sri Lanka", "LK", "LKA", nil, false},
+	{"Sudan", "SD", "SDN", nil, false},
+	{"Suriname", "SR", "SUR", nil, false},
+	{"Svalbard and Jan Mayen", "SJ", "SJM", nil, false},
+	{"Sweden", "SE", "SWE", nil, false},
+	{"Switzerland", "CH", "CHE", nil, false},
+	{"Syria", "SY", "SYR", []string{"Syrian Arab Republic"}, false},
+	{"Taiwan", "TW", "TWN", nil, false},
+	{"Tajikistan", "TJ", "TJK", nil, false},
+	{"Tanzania", "TZ", "TZA", []string{"United Republic of Tanzania"}, false},
+	{"Thailand", "TH", "THA", nil, false},
+	{"Timor-Leste", "TL", "TLS", []string{"East Timor"}, false},
+	{"Togo", "TG", "TGO", nil, false},
+	{"Tokelau", "TK", "TKL", nil, false},
+	{"Tonga", "TO", "TON", nil, false},
+	{"Trinidad and Tobago", "TT", "TTO", nil, false},
+	{"Tunisia", "TN", "TUN", nil, false},
+	{"Turkey", "TR", "TUR", []string{"Türkiye"}, false},
+	{"Turkmenistan", "TM", "TKM", nil, false},
+	{"Turks and Caicos Islands", "TC", "TCA", nil, false},
+	{"Tuvalu", "TV", "TUV", nil, false},
+	{"Uganda", "UG", "UGA", nil, false},
+	{"Ukraine", "UA", "UKR", nil, false},
+	{"United Arab Emirates", "AE", "ARE", []string{"UAE"}, false},
+	{"United Kingdom", "GB", "GBR", []string{"UK", "Great Britain", "United Kingdom of Great Britain and Northern Ireland"}, false},
+	{"United States", "US", "USA", []string{"United States of America", "US"}, false},
+	{"United States Minor Outlying Islands", "UM", "UMI", nil, false},
+	{"Uruguay", "UY", "URY", nil, false},
+	{"Uzbekistan", "UZ", "UZB", nil, false},
+	{"Vanuatu", "VU", "VUT", nil, false},
+	{"Venezuela", "VE", "VEN", []string{"Bolivarian Republic of Venezuela"}, false},
+	{"Vietnam", "VN", "VNM", []string{"Viet Nam"}, false},
+	{"British Virgin Islands", "VG", "VGB", []string{"Virgin Islands (British)"}, false},
+	{"U.S. Virgin Islands", "VI", "VIR", []string{"Virgin Islands (U.S.)", "US Virgin Islands"}, false},
+	{"Wallis and Futuna", "WF", "WLF", nil, false},
+	{"Western Sahara", "EH", "ESH", nil, false},
+	{"Yemen", "YE", "YEM", nil, false},
+	{"Zambia", "ZM", "ZMB", nil, false},
+	{"Zimbabwe", "ZW", "ZWE", nil, false},
+
+	// formerly used countries
+	{"Soviet Union", "SU", "SUN", []string{"USSR", "Union of Soviet Socialist Republics"}, true},
+	{"Yugoslavia", "YU", "YUG", []string{"SFR Yugoslavia", "Federal Republic of Yugoslavia"}, true},
+	{"Czechoslovakia", "CS", "CSK", nil, true},
+	{"East Germany", "DD", "DDR", []string{"German Democratic Republic", "GDR"}, true},
+	{"Serbia and Montenegro", "CS", "SCG", nil, true},
+}
@@ -0,0 +1,180 @@
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llugin/mubi-parser/history"
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// date layout of history windows
+const layout = "2006-01-02"
+
+// Options select entries of the feed
+type Options struct {
+	// Region of the lineup, part of feed title and ids
+	Region string
+	// SelfLink is URL feed is served at, empty when it is not served
+	SelfLink string
+	// Days - arrivals of this many recent days are listed
+	Days int
+	// Leaving adds entries of films leaving tomorrow
+	Leaving bool
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+
+	updated time.Time
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Write writes Atom feed with an entry for each arrival of a film recorded
+// in h in the last Days days, dated when the film first appeared, and
+// optionally entries of films leaving tomorrow. Ratings are taken from
+// movies of the current lineup
+func Write(w io.Writer, h *history.Store, movies []movie.Data, o Options, now time.Time) error {
+	current := map[string]movie.Data{}
+	for _, m := range movies {
+		current[m.MubiLink] = m
+	}
+
+	var entries []atomEntry
+	since := now.AddDate(0, 0, -o.Days)
+	for link, f := range h.Films {
+		for _, win := range f.Windows {
+			appeared, err := time.ParseInLocation(layout, win.Appeared, time.Local)
+			if err != nil || appeared.Before(since) || appeared.After(now) {
+				continue
+			}
+			entries = append(entries, entry(o, "arrived", "", link, f, current[link], appeared))
+		}
+	}
+	if o.Leaving {
+		for _, m := range movies {
+			// listed on the last day, which starts a day before leaving
+			lastDay := m.LeavingAt.Add(-24 * time.Hour)
+			if m.LeavingAt.IsZero() {
+				if m.DaysToWatch != 1 {
+					continue
+				}
+				lastDay = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+			} else if now.Before(lastDay) || !now.Before(m.LeavingAt) {
+				continue
+			}
+			entries = append(entries, entry(o, "leaving", "Leaving tomorrow: ", m.MubiLink, h.Films[m.MubiLink], m, lastDay))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].updated.Equal(entries[j].updated) {
+			return entries[i].updated.After(entries[j].updated)
+		}
+		return entries[i].Title < entries[j].Title
+	})
+
+	title := "MUBI arrivals"
+	if o.Region != "" {
+		title += " (" + o.Region + ")"
+	}
+	updated := now
+	if len(entries) > 0 {
+		updated = entries[0].updated
+	}
+	feed := atomFeed{
+		Title:   title,
+		ID:      tagID(o.Region),
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomAuthor{Name: "mubi-parser"},
+		Links:   []atomLink{{Href: "https://mubi.com/showing", Rel: "alternate"}},
+		Entries: entries,
+	}
+	if o.SelfLink != "" {
+		feed.Links = append(feed.Links, atomLink{Href: o.SelfLink, Rel: "self"})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// entry returns entry of kind about film at link. Film recorded in history
+// is used for films no longer in the lineup, for which m is empty
+func entry(o Options, kind, prefix, link string, f history.Film, m movie.Data, updated time.Time) atomEntry {
+	title, director, year, country := f.Title, f.Director, f.Year, f.Country
+	if m.Title != "" {
+		title, director, year, country = m.Title, m.Directors.String(), m.Year, m.Countries.String()
+	}
+
+	rows := []string{
+		"Director: " + html.EscapeString(director),
+		fmt.Sprintf("Year: %d", year),
+		"Country: " + html.EscapeString(country),
+	}
+	if m.MubiRating != 0 {
+		rows = append(rows, fmt.Sprintf("MUBI: %.1f (%s ratings)", m.MubiRating, m.MubiRatingsNumber))
+	}
+	if m.ImdbRating != 0 {
+		rows = append(rows, fmt.Sprintf("IMDB: %.1f (%s ratings)", m.ImdbRating, m.ImdbRatingsNumber))
+	}
+	body := fmt.Sprintf("<p>%s</p><p><a href=\"%s\">%s</a></p>",
+		strings.Join(rows, "<br>"), html.EscapeString(link), html.EscapeString(link))
+
+	return atomEntry{
+		Title:   fmt.Sprintf("%s%s (%s, %d)", prefix, title, director, year),
+		ID:      tagID(o.Region, kind, path.Base(link), updated.Format(layout)),
+		Updated: updated.Format(time.RFC3339),
+		Link:    atomLink{Href: link, Rel: "alternate"},
+		Content: atomContent{Type: "html", Body: body},
+		updated: updated,
+	}
+}
+
+// tagID returns tag URI of feed or entry identified by parts, unique for
+// the region
+func tagID(region string, parts ...string) string {
+	if region == "" {
+		region = "default"
+	}
+	return "tag:mubi-parser,2019:" + strings.Join(append([]string{strings.ToLower(region)}, parts...), "/")
+}
@@ -0,0 +1,138 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/history"
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func TestWrite(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 0, 0, 0, time.Local)
+	h := &history.Store{Films: map[string]history.Film{
+		"https://mubi.com/films/stalker": {Title: "Stalker", Director: "Andrei Tarkovsky", Year: 1979,
+			Windows: []history.Window{{Appeared: "2026-09-20", Leaving: "2026-10-20"}}},
+		"https://mubi.com/films/mirror": {Title: "Mirror", Director: "Andrei Tarkovsky", Year: 1975,
+			Windows: []history.Window{{Appeared: "2026-10-18", Leaving: "2026-11-17"}}},
+		// departed since, described by history
+		"https://mubi.com/films/solaris": {Title: "Solaris", Director: "Andrei Tarkovsky", Year: 1972,
+			Windows: []history.Window{{Appeared: "2026-10-01", Leaving: "2026-10-10"}}},
+		"https://mubi.com/films/ivan": {Title: "Ivan's Childhood", Director: "Andrei Tarkovsky", Year: 1962,
+			Windows: []history.Window{{Appeared: "2026-08-01", Leaving: "2026-08-31"}}},
+	}}
+	movies := []movie.Data{
+		{Title: "Stalker", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1979,
+			MubiLink: "https://mubi.com/films/stalker", MubiRating: 4.5, MubiRatingsNumber: 12345,
+			DaysToWatch: 1, LeavingAt: time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)},
+		{Title: "Mirror", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1975,
+			MubiLink: "https://mubi.com/films/mirror", ImdbRating: 8, ImdbRatingsNumber: 50000,
+			DaysToWatch: 29, LeavingAt: time.Date(2026, 11, 17, 0, 0, 0, 0, time.Local)},
+	}
+
+	tests := []struct {
+		name    string
+		o       Options
+		entries []string
+	}{
+		{"arrivals", Options{Days: 30},
+			[]string{"Mirror (Andrei Tarkovsky, 1975)", "Solaris (Andrei Tarkovsky, 1972)", "Stalker (Andrei Tarkovsky, 1979)"}},
+		{"recent arrivals", Options{Days: 7},
+			[]string{"Mirror (Andrei Tarkovsky, 1975)"}},
+		{"with leaving", Options{Days: 7, Leaving: true},
+			[]string{"Leaving tomorrow: Stalker (Andrei Tarkovsky, 1979)", "Mirror (Andrei Tarkovsky, 1975)"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, h, movies, tt.o, now); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var f atomFeed
+		if err := xml.Unmarshal(buf.Bytes(), &f); err != nil {
+			t.Fatalf("%s: %v\n%s", tt.name, err, buf.String())
+		}
+		var titles []string
+		for _, e := range f.Entries {
+			titles = append(titles, e.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.entries) {
+			t.Errorf("%s: entries %q, want %q", tt.name, titles, tt.entries)
+		}
+	}
+}
+
+func TestWriteEntries(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 0, 0, 0, time.Local)
+	h := &history.Store{Films: map[string]history.Film{
+		"https://mubi.com/films/stalker": {Title: "Stalker", Director: "Andrei Tarkovsky", Year: 1979,
+			Windows: []history.Window{{Appeared: "2026-10-18", Leaving: "2026-11-17"}}},
+	}}
+	movies := []movie.Data{{Title: "Stalker", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1979,
+		Countries: movie.List{"Soviet Union"}, MubiLink: "https://mubi.com/films/stalker",
+		MubiRating: 4.5, MubiRatingsNumber: 12345}}
+
+	var buf bytes.Buffer
+	o := Options{Region: "GB", SelfLink: "http://localhost:8080/feed", Days: 30}
+	if err := Write(&buf, h, movies, o, now); err != nil {
+		t.Fatal(err)
+	}
+	var f atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Title != "MUBI arrivals (GB)" || f.ID != "tag:mubi-parser,2019:gb" {
+		t.Errorf("feed %q %q", f.Title, f.ID)
+	}
+	if len(f.Links) != 2 || f.Links[1].Rel != "self" || f.Links[1].Href != o.SelfLink {
+		t.Errorf("links %+v, want alternate and self", f.Links)
+	}
+	if len(f.Entries) != 1 {
+		t.Fatalf("entries %+v, want one", f.Entries)
+	}
+	e := f.Entries[0]
+	updated := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local).Format(time.RFC3339)
+	if e.ID != "tag:mubi-parser,2019:gb/arrived/stalker/2026-10-18" || e.Updated != updated || f.Updated != updated {
+		t.Errorf("entry %q updated %q, feed updated %q, want %s", e.ID, e.Updated, f.Updated, updated)
+	}
+	for _, s := range []string{"Country: Soviet Union", "MUBI: 4.5 (12,345 ratings)", `href="https://mubi.com/films/stalker"`} {
+		if !strings.Contains(e.Content.Body, s) {
+			t.Errorf("%q not in entry content %q", s, e.Content.Body)
+		}
+	}
+	if strings.Contains(e.Content.Body, "IMDB") {
+		t.Errorf("IMDB rating of film without one in %q", e.Content.Body)
+	}
+}
+
+func TestWriteLeavingWindow(t *testing.T) {
+	leaving := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	h := &history.Store{Films: map[string]history.Film{}}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		m    movie.Data
+		want bool
+	}{
+		{"day before last", leaving.Add(-30 * time.Hour), movie.Data{LeavingAt: leaving}, false},
+		{"last day", leaving.Add(-time.Hour), movie.Data{LeavingAt: leaving}, true},
+		{"already left", leaving.Add(time.Hour), movie.Data{LeavingAt: leaving, DaysToWatch: 1}, false},
+		{"no leaving time, one day left", leaving.Add(-time.Hour), movie.Data{DaysToWatch: 1}, true},
+		{"no leaving time, more days left", leaving.Add(-time.Hour), movie.Data{DaysToWatch: 2}, false},
+	}
+	for _, tt := range tests {
+		tt.m.Title, tt.m.MubiLink = "Stalker", "https://mubi.com/films/stalker"
+		var buf bytes.Buffer
+		if err := Write(&buf, h, []movie.Data{tt.m}, Options{Leaving: true}, tt.now); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(buf.String(), "Leaving tomorrow: Stalker"); got != tt.want {
+			t.Errorf("%s: leaving entry %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package fetch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llugin/mubi-parser/debugging"
+)
+
+var (
+	// Retries - number of retries of transient failures
+	Retries = 3
+	// Timeout of a single request
+	Timeout = 30 * time.Second
+	// Backoff - delay before the first retry, doubled with each next one
+	Backoff = time.Second
+	// MaxBackoff - the longest delay between retries
+	MaxBackoff = 30 * time.Second
+)
+
+var (
+	failures   []error
+	failuresMu sync.Mutex
+)
+
+// StatusError is a response with non-2xx status
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is delay requested by server, zero if not given
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.URL, e.Status)
+}
+
+// Temporary tells if request may succeed when retried
+func (e *StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Get sends GET request to host, waiting for its rate limit, and returns
+// response body. Non-2xx responses are errors. Network errors and transient
+// statuses are retried with exponential backoff and jitter, honoring
+// Retry-After. Requests which failed for good are kept for Failures
+func Get(req *http.Request, host string) ([]byte, error) {
+	client := &http.Client{Timeout: Timeout}
+	var err error
+	for attempt := 0; ; attempt++ {
+		Wait(host)
+		var body []byte
+		body, err = get(client, req)
+		if err == nil {
+			return body, nil
+		}
+
+		statusErr, isStatus := err.(*StatusError)
+		if isStatus && !statusErr.Temporary() || attempt >= Retries {
+			break
+		}
+		delay := backoff(attempt)
+		if isStatus && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		debugging.Log().Printf("%v, retrying in %v\n", err, delay.Round(time.Millisecond))
+		time.Sleep(delay)
+	}
+
+	failuresMu.Lock()
+	failures = append(failures, err)
+	failuresMu.Unlock()
+	return nil, err
+}
+
+func get(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		if ue, ok := err.(*url.Error); ok {
+			ue.URL = redact(req.URL)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{
+			URL:        redact(req.URL),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// redact hides values of key query parameters, like OMDB apikey, so that
+// errors can be shown
+func redact(u *url.URL) string {
+	q := u.Query()
+	hidden := false
+	for k := range q {
+		if strings.Contains(strings.ToLower(k), "key") {
+			q.Set(k, "xxx")
+			hidden = true
+		}
+	}
+	if !hidden {
+		return u.String()
+	}
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+// backoff returns delay before retry following given attempt, between half
+// and full of exponentially growing delay
+func backoff(attempt int) time.Duration {
+	d := Backoff << uint(attempt)
+	if d > MaxBackoff || d <= 0 {
+		d = MaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses Retry-After header given in seconds or as a date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Failures returns requests which failed for good since the last reset
+func Failures() []error {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	return append([]error(nil), failures...)
+}
+
+// ResetFailures forgets failed requests
+func ResetFailures() {
+	failuresMu.Lock()
+	failures = nil
+	failuresMu.Unlock()
+}
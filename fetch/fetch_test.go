@@ -0,0 +1,109 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/debugging"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "fetch")
+	if err != nil {
+		panic(err)
+	}
+	debugging.InitLogger(dir, false)
+	Backoff, MaxBackoff = time.Millisecond, time.Millisecond
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		requests int32
+		ok       bool
+	}{
+		{"success", []int{200}, 3, 1, true},
+		{"retried 503", []int{503, 503, 200}, 3, 3, true},
+		{"retried 429", []int{429, 200}, 3, 2, true},
+		{"retries used up", []int{503, 503, 503, 503, 200}, 3, 4, false},
+		{"not found is not retried", []int{404, 200}, 3, 1, false},
+		{"no retries", []int{503, 200}, 0, 1, false},
+		{"negative retries", []int{503, 200}, -1, 1, false},
+	}
+	defer func(r int) { Retries = r }(Retries)
+	for _, tt := range tests {
+		var requests int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&requests, 1)
+			w.WriteHeader(tt.statuses[n-1])
+			w.Write([]byte("body"))
+		}))
+		ResetFailures()
+		Retries = tt.retries
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		body, err := Get(req, "test")
+		srv.Close()
+
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if tt.ok && string(body) != "body" {
+			t.Errorf("%s: body %q", tt.name, body)
+		}
+		if requests != tt.requests {
+			t.Errorf("%s: %d requests, want %d", tt.name, requests, tt.requests)
+		}
+		if failed := len(Failures()) > 0; failed == tt.ok {
+			t.Errorf("%s: failures %v, want ok %v", tt.name, Failures(), tt.ok)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"0", 0},
+		{"-1", 0},
+		{"soon", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := retryAfter(future); got <= 59*time.Minute || got > time.Hour {
+		t.Errorf("retryAfter(%q) = %v, want about an hour", future, got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://mubi.com/showing", "https://mubi.com/showing"},
+		{"http://www.omdbapi.com/?t=Stalker&apikey=secret", "http://www.omdbapi.com/?apikey=xxx&t=Stalker"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.in)
+		if got := redact(u); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
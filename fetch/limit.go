@@ -0,0 +1,77 @@
+package fetch
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Jitter - random delay added to waits for rate limits, as a fraction of
+// interval between requests
+var Jitter = 0.2
+
+// bucket is a token bucket refilled at rate tokens per second
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+var (
+	buckets   = map[string]*bucket{}
+	bucketsMu sync.Mutex
+)
+
+// SetLimit limits requests to host to rate per second, allowing bursts of
+// up to burst requests. Rate equal to or less than zero removes the limit
+func SetLimit(host string, rate float64, burst int) {
+	bucketsMu.Lock()
+	defer bucketsMu.Unlock()
+	if rate <= 0 {
+		delete(buckets, host)
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	buckets[host] = &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until request to host is allowed by its limit. Requests of
+// all goroutines to the same host share the limit
+func Wait(host string) {
+	bucketsMu.Lock()
+	b, ok := buckets[host]
+	bucketsMu.Unlock()
+	if !ok {
+		return
+	}
+	if d := b.reserve(); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// reserve takes a token and returns how long to wait until it is available
+func (b *bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	// tokens go below zero for requests queued behind each other
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if Jitter > 0 {
+		wait += time.Duration(rand.Float64() * Jitter / b.rate * float64(time.Second))
+	}
+	return wait
+}
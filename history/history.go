@@ -0,0 +1,230 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+const (
+	jsonFileName = "mubi-history.json"
+
+	// time layout for snapshot dates
+	layout = "2006-01-02"
+)
+
+// JSONPath is a path to directory with history json file
+var JSONPath = ""
+
+// Snapshot is a lineup of movies seen on a given day, identified by links
+type Snapshot struct {
+	Date  string   `json:"date"`
+	Links []string `json:"links"`
+}
+
+// Window is a period when movie was available on MUBI
+type Window struct {
+	Appeared string `json:"appeared"`
+	Leaving  string `json:"leaving"`
+}
+
+// Film is a movie ever seen in lineup with its showing windows
+type Film struct {
+	Title    string   `json:"title"`
+	Director string   `json:"director"`
+	Year     int      `json:"year"`
+	Country  string   `json:"country"`
+	Genre    string   `json:"genre"`
+	Windows  []Window `json:"windows"`
+}
+
+// Store keeps lineup snapshots and all films ever seen, keyed by MUBI link
+type Store struct {
+	Snapshots []Snapshot      `json:"snapshots"`
+	Films     map[string]Film `json:"films"`
+}
+
+// Diff lists links of movies that arrived and departed between snapshots
+type Diff struct {
+	From     string
+	To       string
+	Arrived  []string
+	Departed []string
+}
+
+func jsonfile() string {
+	return filepath.Join(JSONPath, movie.RegionFileName(jsonFileName))
+}
+
+// Read reads history from json file. Missing file results in empty store
+func Read() (*Store, error) {
+	s := &Store{Films: map[string]Film{}}
+	out, err := ioutil.ReadFile(jsonfile())
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(out, s); err != nil {
+		return s, err
+	}
+	if s.Films == nil {
+		s.Films = map[string]Film{}
+	}
+	return s, nil
+}
+
+// Write writes history to json file
+func (s *Store) Write() error {
+	out, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return err
+	}
+	return movie.WriteFile(jsonfile(), out)
+}
+
+// Record stores lineup of movies retrieved on date. Lineup recorded earlier
+// on the same day is replaced
+func (s *Store) Record(movies []movie.Data, date time.Time) {
+	snap := Snapshot{Date: date.Format(layout)}
+	for _, m := range movies {
+		snap.Links = append(snap.Links, m.MubiLink)
+		s.recordFilm(m)
+	}
+	sort.Strings(snap.Links)
+
+	for i, old := range s.Snapshots {
+		if old.Date == snap.Date {
+			s.Snapshots[i] = snap
+			return
+		}
+	}
+	s.Snapshots = append(s.Snapshots, snap)
+	sort.Slice(s.Snapshots, func(i, j int) bool {
+		return s.Snapshots[i].Date < s.Snapshots[j].Date
+	})
+}
+
+func (s *Store) recordFilm(m movie.Data) {
+	f := s.Films[m.MubiLink]
+	f.Title, f.Director, f.Year = m.Title, m.Directors.String(), m.Year
+	f.Country, f.Genre = m.Countries.String(), m.Genres.String()
+
+	appeared, err := m.ParseDateAppeared()
+	if err != nil {
+		s.Films[m.MubiLink] = f
+		return
+	}
+	leaving, _ := m.ParseDateLeaving()
+	w := Window{appeared.Format(layout), leaving.Format(layout)}
+
+	if n := len(f.Windows); n > 0 && f.Windows[n-1].Leaving >= w.Appeared {
+		// same showing window, dates may shift by a day between retrievals
+		if w.Leaving > f.Windows[n-1].Leaving {
+			f.Windows[n-1].Leaving = w.Leaving
+		}
+	} else {
+		f.Windows = append(f.Windows, w)
+	}
+	s.Films[m.MubiLink] = f
+}
+
+// Movies returns all films ever seen as movies with basic data
+func (s *Store) Movies() []movie.Data {
+	var movies []movie.Data
+	for link, f := range s.Films {
+		movies = append(movies, movie.Data{
+			Title:     f.Title,
+			Directors: movie.ParseList(f.Director),
+			Year:      f.Year,
+			Countries: movie.ParseList(f.Country),
+			Genres:    movie.ParseList(f.Genre),
+			MubiLink:  link,
+		})
+	}
+	return movies
+}
+
+// Snapshot returns lineup recorded on date
+func (s *Store) Snapshot(date string) (Snapshot, bool) {
+	for _, snap := range s.Snapshots {
+		if snap.Date == date {
+			return snap, true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// Latest returns the most recent snapshot
+func (s *Store) Latest() (Snapshot, bool) {
+	if len(s.Snapshots) == 0 {
+		return Snapshot{}, false
+	}
+	return s.Snapshots[len(s.Snapshots)-1], true
+}
+
+// Diff compares lineups recorded on two dates. Empty to stands for the
+// most recent snapshot and empty from for the one before to
+func (s *Store) Diff(from, to string) (Diff, error) {
+	n := len(s.Snapshots)
+	if n == 0 {
+		return Diff{}, fmt.Errorf("No lineups recorded yet")
+	}
+	if to == "" {
+		to = s.Snapshots[n-1].Date
+	}
+	i := s.index(to)
+	if i < 0 {
+		return Diff{}, fmt.Errorf("No lineup recorded on %s", to)
+	}
+	if from == "" {
+		if i == 0 {
+			return Diff{}, fmt.Errorf("No lineup recorded before %s", to)
+		}
+		from = s.Snapshots[i-1].Date
+	}
+	j := s.index(from)
+	if j < 0 {
+		return Diff{}, fmt.Errorf("No lineup recorded on %s", from)
+	}
+	return diff(s.Snapshots[j], s.Snapshots[i]), nil
+}
+
+// index returns index of snapshot recorded on date, -1 if there is none
+func (s *Store) index(date string) int {
+	for i, snap := range s.Snapshots {
+		if snap.Date == date {
+			return i
+		}
+	}
+	return -1
+}
+
+func diff(prev, curr Snapshot) Diff {
+	d := Diff{From: prev.Date, To: curr.Date}
+	d.Arrived = subtract(curr.Links, prev.Links)
+	d.Departed = subtract(prev.Links, curr.Links)
+	return d
+}
+
+// subtract returns elements of a missing in b
+func subtract(a, b []string) []string {
+	in := map[string]bool{}
+	for _, l := range b {
+		in[l] = true
+	}
+	var out []string
+	for _, l := range a {
+		if !in[l] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
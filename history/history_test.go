@@ -0,0 +1,111 @@
+package history
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func film(link, appeared string) movie.Data {
+	return movie.Data{Title: link, Directors: movie.List{"Director"}, Year: 2000,
+		MubiLink: link, DateAppeared: appeared}
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, 10, d, 12, 0, 0, 0, time.UTC)
+}
+
+// store has lineups of 17, 18 and 19 October 2026: a and b, then a and c,
+// then c and d
+func store() *Store {
+	s := &Store{Films: map[string]Film{}}
+	s.Record([]movie.Data{film("a", "2026-9-20"), film("b", "2026-9-25")}, day(17))
+	s.Record([]movie.Data{film("c", "2026-10-18"), film("a", "2026-9-20")}, day(18))
+	s.Record([]movie.Data{film("c", "2026-10-18"), film("d", "2026-10-19")}, day(19))
+	return s
+}
+
+func TestRecord(t *testing.T) {
+	s := store()
+	var dates []string
+	for _, snap := range s.Snapshots {
+		dates = append(dates, fmt.Sprint(snap.Date, snap.Links))
+	}
+	want := []string{"2026-10-17[a b]", "2026-10-18[a c]", "2026-10-19[c d]"}
+	if !reflect.DeepEqual(dates, want) {
+		t.Errorf("snapshots %v, want %v", dates, want)
+	}
+
+	// recorded again the same day, replaces the lineup
+	s.Record([]movie.Data{film("c", "2026-10-18")}, day(19).Add(time.Hour))
+	if snap, _ := s.Latest(); len(s.Snapshots) != 3 || !reflect.DeepEqual(snap.Links, []string{"c"}) {
+		t.Errorf("lineup recorded twice a day: %+v", s.Snapshots)
+	}
+
+	// earlier lineup is sorted by date
+	s.Record([]movie.Data{film("a", "2026-9-20")}, day(16))
+	if s.Snapshots[0].Date != "2026-10-16" {
+		t.Errorf("first snapshot %s, want 2026-10-16", s.Snapshots[0].Date)
+	}
+}
+
+func TestRecordWindows(t *testing.T) {
+	s := &Store{Films: map[string]Film{}}
+	s.Record([]movie.Data{film("a", "2026-9-20")}, day(1))
+	// dates shift by a day between retrievals within the same window
+	s.Record([]movie.Data{film("a", "2026-9-21")}, day(2))
+	// shown again later
+	s.Record([]movie.Data{film("a", "2027-1-10")}, day(3))
+	want := []Window{{"2026-09-20", "2026-10-21"}, {"2027-01-10", "2027-02-09"}}
+	if got := s.Films["a"].Windows; !reflect.DeepEqual(got, want) {
+		t.Errorf("windows %v, want %v", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     Diff
+	}{
+		{"", "", Diff{"2026-10-18", "2026-10-19", []string{"d"}, []string{"a"}}},
+		{"", "2026-10-18", Diff{"2026-10-17", "2026-10-18", []string{"c"}, []string{"b"}}},
+		{"2026-10-17", "", Diff{"2026-10-17", "2026-10-19", []string{"c", "d"}, []string{"a", "b"}}},
+		{"2026-10-17", "2026-10-18", Diff{"2026-10-17", "2026-10-18", []string{"c"}, []string{"b"}}},
+		{"2026-10-19", "2026-10-19", Diff{From: "2026-10-19", To: "2026-10-19"}},
+	}
+	s := store()
+	for _, tt := range tests {
+		got, err := s.Diff(tt.from, tt.to)
+		if err != nil {
+			t.Errorf("Diff(%q, %q): %v", tt.from, tt.to, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Diff(%q, %q) = %+v, want %+v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        *Store
+		from, to string
+		want     string
+	}{
+		{"empty", &Store{}, "", "", "No lineups recorded yet"},
+		{"single lineup", &Store{Snapshots: []Snapshot{{Date: "2026-10-19"}}}, "", "",
+			"No lineup recorded before 2026-10-19"},
+		{"from first lineup", store(), "", "2026-10-17", "No lineup recorded before 2026-10-17"},
+		{"unknown to", store(), "", "2026-10-01", "No lineup recorded on 2026-10-01"},
+		{"unknown from", store(), "2026-10-01", "", "No lineup recorded on 2026-10-01"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.s.Diff(tt.from, tt.to); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -2,141 +2,180 @@ package imdb
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unicode"
 
 	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/fetch"
 	"github.com/llugin/mubi-parser/movie"
-	"github.com/llugin/mubi-parser/mubi"
+	"github.com/llugin/mubi-parser/workers"
 	"golang.org/x/text/transform"
 	"golang.org/x/text/unicode/norm"
 )
 
 const (
 	urlFormat = "http://www.omdbapi.com/?t=%s&y=%v&type=movie&apikey=%s"
+
+	// Source is recorded as source of movie fields filled from OMDB
+	Source = "omdb"
+
+	// Host is OMDB host name requests are rate limited by
+	Host = "www.omdbapi.com"
+
+	// Stage is name of the stage looking up ratings
+	Stage = "OMDB ratings"
 )
 
 var (
-	//APICount - OMDB API call counter
-	APICount int
+	//APICount - OMDB API call counter, updated atomically
+	APICount int64
 	//APIKey - OMDB api key
 	APIKey string
-	//Sleep - sleep time between API calls in miliseconds
-	Sleep = 200
+	//Workers - number of movies looked up at once
+	Workers = 4
+	// Fields - fields, named as in json, looked up in OMDB
+	Fields = []string{"IMDB rating", "IMDB ratings num", "IMDB id"}
 )
 
 type apiResp struct {
 	ImdbRating string `json:"imdbRating"`
 	ImdbVotes  string `json:"imdbVotes"`
+	ImdbID     string `json:"imdbID"`
 	Response   string `json:"Response"`
 	Director   string `json:"Director"`
+	Country    string `json:"Country"`
+	Genre      string `json:"Genre"`
 	Error      string `json:"Error"`
 }
 
-//SendRatings get movie ratings of imdb movies
+//SendRatings get movie ratings of imdb movies, in order they were received.
+// Movies with no stale OMDB fields are passed on
 func SendRatings(done <-chan struct{}, in <-chan movie.Data) <-chan movie.Data {
-	out := make(chan movie.Data, mubi.MaxMovies)
-
-	go func() {
-		defer close(out)
-		for m := range in {
-			if APIKey != "" {
-				time.Sleep(time.Duration(Sleep) * time.Millisecond)
-				obtainMovieRating(&m)
-			} else {
-				debugging.Log().Println("no OMDB Api Key")
-			}
-			select {
-			case out <- m:
-			case <-done:
-				return
-			}
+	return workers.Map(done, in, Workers, Stage, func(m movie.Data) movie.Data {
+		if !m.Refreshes(Fields) {
+			return m
 		}
-	}()
-
-	return out
+		if APIKey != "" {
+			obtainMovieRating(&m)
+		} else {
+			debugging.Log().Println("no OMDB Api Key")
+		}
+		return m
+	})
 }
 
 func obtainMovieRating(m *movie.Data) {
-	var ar apiResp
-	var err error
-
-	if ar, err = getAPIResp(m.Title, m.Director, m.Year); err == nil {
-		goto Found
-	} else {
-		debugging.Log().Println(err)
+	type lookup struct {
+		title     string
+		directors movie.List
+		year      int
 	}
+	lookups := []lookup{{m.Title, m.Directors, m.Year}}
 	// Try alternative title
 	if m.AltTitle != "" {
-		if ar, err = getAPIResp(m.AltTitle, m.Director, m.Year); err == nil {
-			goto Found
-		} else {
-			debugging.Log().Println(err)
-		}
+		lookups = append(lookups, lookup{m.AltTitle, m.Directors, m.Year})
 	}
+	lookups = append(lookups,
+		// Try with approximate years (+1/-1 year)
+		lookup{m.Title, m.Directors, m.Year - 1},
+		lookup{m.Title, m.Directors, m.Year + 1},
+		// Try with normalized director name
+		lookup{m.Title, normalizeNames(m.Directors), m.Year},
+	)
 
-	// Try with approximate years (+1/-1 year)
-	if ar, err = getAPIResp(m.Title, m.Director, m.Year-1); err == nil {
-		goto Found
-	} else {
+	var ar apiResp
+	var err error
+	for _, l := range lookups {
+		if ar, err = getAPIResp(l.title, l.directors, l.year); err == nil {
+			break
+		}
 		debugging.Log().Println(err)
+		if _, failed := err.(fetchError); failed {
+			// OMDB could not be asked, other lookups would fail too
+			break
+		}
+	}
+	if err != nil {
+		// no lookup matched, fill nothing from a rejected response
+		m.Fail(Stage, err, Fields...)
+		return
 	}
 
-	if ar, err = getAPIResp(m.Title, m.Director, m.Year+1); err == nil {
-		goto Found
+	// OMDB fills IMDB fields and fields MUBI page did not provide
+	var d movie.Data
+	if f, err := strconv.ParseFloat(ar.ImdbRating, 32); err == nil {
+		d.ImdbRating = f
 	} else {
-		debugging.Log().Println(err)
+		debugging.Log().Printf("Could not parse imdb rating '%s' as a float\n", ar.ImdbRating)
 	}
 
-	// Try with normalized director name
-	if ar, err = getAPIResp(m.Title, normalizeName(m.Director), m.Year); err == nil {
-		goto Found
+	if votes, err := movie.ParseVotes(ar.ImdbVotes); err == nil {
+		d.ImdbRatingsNumber = votes
 	} else {
 		debugging.Log().Println(err)
 	}
+	d.ImdbID = ar.ImdbID
+	d.Genres = movie.ParseList(ar.Genre)
+	d.SetCountries(movie.ParseList(ar.Country))
 
-Found:
-	if f, err := strconv.ParseFloat(ar.ImdbRating, 32); err == nil {
-		m.ImdbRating = f
-	} else {
-		debugging.Log().Printf("Could not parse imdb rating '%s' as a float\n", ar.ImdbRating)
-		m.ImdbRating = 0.0
+	// the accepted match replaces stored IMDB values it has, others are kept
+	if d.ImdbRating != 0 {
+		m.ImdbRating = 0
 	}
-
-	m.ImdbRatingsNumber = ar.ImdbVotes
+	if d.ImdbRatingsNumber != 0 {
+		m.ImdbRatingsNumber = 0
+	}
+	if d.ImdbID != "" {
+		m.ImdbID = ""
+	}
+	m.Fill(d, Source)
+	m.SetFetched(Fields, time.Now())
 }
 
-func getAPIResp(title, director string, year int) (apiResp, error) {
-	APICount++
+func getAPIResp(title string, directors movie.List, year int) (apiResp, error) {
+	atomic.AddInt64(&APICount, 1)
 	var ar apiResp
-	var err error
 
 	url := fmt.Sprintf(urlFormat, strings.Replace(title, " ", "+", -1), year, APIKey)
-	resp, err := http.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return ar, err
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := fetch.Get(req, Host)
 	if err != nil {
-		return ar, err
+		return ar, fetchError{err}
 	}
 	err = json.Unmarshal(body, &ar)
 	if err != nil && ar.Response != "True" {
-		err = fmt.Errorf(ar.Error)
+		err = errors.New(ar.Error)
 	}
-	if ar.Director != director {
+	// any shared director is enough, MUBI and OMDB often list co-directors differently
+	if !directors.Intersects(movie.ParseList(ar.Director)) {
 		err = fmt.Errorf("Wrong director")
 	}
 	return ar, err
 }
 
+// fetchError is failure to get response from OMDB, as opposed to OMDB not
+// finding the movie
+type fetchError struct {
+	error
+}
+
+func normalizeNames(in movie.List) movie.List {
+	var out movie.List
+	for _, name := range in {
+		out = append(out, normalizeName(name))
+	}
+	return out
+}
+
 func normalizeName(in string) string {
 	isMn := func(r rune) bool {
 		return unicode.Is(unicode.Mn, r)
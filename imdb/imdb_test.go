@@ -0,0 +1,120 @@
+package imdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// omdbResponses maps looked up titles to OMDB responses, other titles are
+// not found
+type omdbResponses map[string]string
+
+func (o omdbResponses) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := o[req.URL.Query().Get("t")]
+	if !ok {
+		body = `{"Response":"False","Error":"Movie not found!"}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "imdb")
+	if err != nil {
+		panic(err)
+	}
+	debugging.InitLogger(dir, false)
+	APIKey = "key"
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func withOMDB(t *testing.T, o omdbResponses) {
+	transport := http.DefaultTransport
+	http.DefaultTransport = o
+	t.Cleanup(func() { http.DefaultTransport = transport })
+}
+
+func TestObtainMovieRating(t *testing.T) {
+	withOMDB(t, omdbResponses{
+		"Stalker": `{"Response":"True","imdbRating":"8.1","imdbVotes":"150,123",
+			"imdbID":"tt0079944","Director":"Andrei Tarkovsky","Genre":"Drama, Sci-Fi",
+			"Country":"Soviet Union"}`,
+		"Solaris": `{"Response":"True","imdbRating":"6.2","imdbVotes":"100,000",
+			"imdbID":"tt0307479","Director":"Steven Soderbergh"}`,
+	})
+
+	tests := []struct {
+		name   string
+		in     movie.Data
+		rating float64
+		votes  movie.Votes
+		id     string
+		failed bool
+	}{
+		{"match", movie.Data{Title: "Stalker", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1979},
+			8.1, 150123, "tt0079944", false},
+		{"wrong director", movie.Data{Title: "Solaris", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1972},
+			0, 0, "", true},
+		{"not found", movie.Data{Title: "Mirror", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1975},
+			0, 0, "", true},
+	}
+	for _, tt := range tests {
+		m := tt.in
+		obtainMovieRating(&m)
+		if float32(m.ImdbRating) != float32(tt.rating) || m.ImdbRatingsNumber != tt.votes || m.ImdbID != tt.id {
+			t.Errorf("%s: got %v, %v, %q, want %v, %v, %q", tt.name,
+				m.ImdbRating, m.ImdbRatingsNumber, m.ImdbID, tt.rating, tt.votes, tt.id)
+		}
+		if m.FailedStage(Stage) != tt.failed || m.Unknown("IMDB rating") != tt.failed {
+			t.Errorf("%s: failures %+v, want failed %v", tt.name, m.Failures, tt.failed)
+		}
+	}
+}
+
+func TestObtainMovieRatingRefresh(t *testing.T) {
+	withOMDB(t, omdbResponses{
+		"Stalker": `{"Response":"True","imdbRating":"8.2","imdbVotes":"160,000",
+			"imdbID":"tt0079944","Director":"Andrei Tarkovsky"}`,
+		"Mirror": `{"Response":"True","imdbRating":"N/A","imdbVotes":"N/A",
+			"imdbID":"tt0072443","Director":"Andrei Tarkovsky"}`,
+		"Solaris": `{"Response":"True","imdbRating":"6.2","imdbVotes":"100,000",
+			"imdbID":"tt0307479","Director":"Steven Soderbergh"}`,
+	})
+	stored := func(title string) movie.Data {
+		return movie.Data{Title: title, Directors: movie.List{"Andrei Tarkovsky"}, Year: 1979,
+			ImdbRating: 8, ImdbRatingsNumber: 1000, ImdbID: "tt0000001", Stale: Fields}
+	}
+
+	tests := []struct {
+		name   string
+		in     movie.Data
+		rating float64
+		votes  movie.Votes
+		id     string
+	}{
+		{"new match", stored("Stalker"), 8.2, 160000, "tt0079944"},
+		{"match without ratings", stored("Mirror"), 8, 1000, "tt0072443"},
+		{"wrong director", stored("Solaris"), 8, 1000, "tt0000001"},
+		{"not found", stored("Nostalghia"), 8, 1000, "tt0000001"},
+	}
+	for _, tt := range tests {
+		m := tt.in
+		obtainMovieRating(&m)
+		if float32(m.ImdbRating) != float32(tt.rating) || m.ImdbRatingsNumber != tt.votes || m.ImdbID != tt.id {
+			t.Errorf("%s: got %v, %v, %q, want %v, %v, %q", tt.name,
+				m.ImdbRating, m.ImdbRatingsNumber, m.ImdbID, tt.rating, tt.votes, tt.id)
+		}
+	}
+}
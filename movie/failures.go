@@ -0,0 +1,50 @@
+package movie
+
+import "reflect"
+
+// Failure is an enrichment stage which failed for a movie, leaving fields
+// it provides empty or as they were after an earlier update
+type Failure struct {
+	Stage string `json:"stage"`
+	// Fields are named as in json
+	Fields []string `json:"fields"`
+	Error  string   `json:"error"`
+}
+
+// Fail records that stage providing fields, named as in json, failed with
+// err
+func (d *Data) Fail(stage string, err error, fields ...string) {
+	d.Failures = append(d.Failures, Failure{Stage: stage, Fields: fields, Error: err.Error()})
+}
+
+// FailedStage tells if stage failed for the movie
+func (d *Data) FailedStage(stage string) bool {
+	for _, f := range d.Failures {
+		if f.Stage == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// Unknown tells if field, named as in json, is empty because the stage
+// providing it failed, as opposed to the field having no value
+func (d *Data) Unknown(field string) bool {
+	failed := false
+	for _, f := range d.Failures {
+		for _, name := range f.Fields {
+			failed = failed || name == field
+		}
+	}
+	if !failed {
+		return false
+	}
+	v := reflect.ValueOf(d).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == field {
+			return v.Field(i).IsZero()
+		}
+	}
+	return false
+}
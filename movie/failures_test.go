@@ -0,0 +1,45 @@
+package movie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnknown(t *testing.T) {
+	d := Data{Title: "Stalker", ImdbID: "tt0079944"}
+	d.Fail("OMDB ratings", errors.New("Movie not found!"), "IMDB rating", "IMDB ratings num", "IMDB id")
+
+	tests := []struct {
+		field string
+		want  bool
+	}{
+		// empty field of the failed stage
+		{"IMDB rating", true},
+		{"IMDB ratings num", true},
+		// kept from an earlier update
+		{"IMDB id", false},
+		// empty field of a stage which did not fail
+		{"MUBI rating", false},
+		{"title", false},
+		{"no such field", false},
+	}
+	for _, tt := range tests {
+		if got := d.Unknown(tt.field); got != tt.want {
+			t.Errorf("Unknown(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestFailedStage(t *testing.T) {
+	var d Data
+	if d.FailedStage("MUBI details") {
+		t.Errorf("stage failed for a movie without failures")
+	}
+	d.Fail("MUBI details", errors.New("GET https://mubi.com/films/stalker: 503 Service Unavailable"))
+	if !d.FailedStage("MUBI details") || d.FailedStage("OMDB ratings") {
+		t.Errorf("failures %+v", d.Failures)
+	}
+	if d.Failures[0].Error != "GET https://mubi.com/films/stalker: 503 Service Unavailable" {
+		t.Errorf("error %q", d.Failures[0].Error)
+	}
+}
@@ -0,0 +1,129 @@
+package movie
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// SetFetched records that fields, named as in json, were fetched at t
+func (d *Data) SetFetched(fields []string, t time.Time) {
+	if d.Fetched == nil {
+		d.Fetched = map[string]time.Time{}
+	}
+	for _, f := range fields {
+		d.Fetched[f] = t
+	}
+}
+
+// StaleFields returns fields of policy, which maps fields named as in json
+// to their max age, fetched longer ago than their max age or never
+func (d *Data) StaleFields(policy map[string]time.Duration, now time.Time) []string {
+	stale := []string{}
+	for field, maxAge := range policy {
+		if fetched, ok := d.Fetched[field]; !ok || now.Sub(fetched) > maxAge {
+			stale = append(stale, field)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// Refreshes tells if stage providing fields should run for the movie:
+// when it is fetched anew or any of fields is stale
+func (d *Data) Refreshes(fields []string) bool {
+	if d.Stale == nil {
+		return true
+	}
+	for _, f := range fields {
+		if d.isStale(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClearStale empties stale fields among fields, so that stage providing
+// them fills them anew
+func (d *Data) ClearStale(fields []string) {
+	v := reflect.ValueOf(d).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" || !d.isStale(name) {
+			continue
+		}
+		for _, f := range fields {
+			if f == name {
+				v.Field(i).Set(reflect.Zero(t.Field(i).Type))
+			}
+		}
+	}
+}
+
+func (d *Data) isStale(field string) bool {
+	for _, f := range d.Stale {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Rollover is time of day MUBI lineup changes at
+type Rollover struct {
+	Hour, Min int
+	Location  *time.Location
+}
+
+// ParseRollover parses rollover time of day given like "15:04" in IANA
+// timezone zone, local timezone when zone is empty
+func ParseRollover(clock, zone string) (Rollover, error) {
+	var r Rollover
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return r, fmt.Errorf("Rollover time '%s' is not like 15:04", clock)
+	}
+	r.Hour, r.Min = t.Hour(), t.Minute()
+	r.Location = time.Local
+	if zone != "" {
+		if r.Location, err = time.LoadLocation(zone); err != nil {
+			return r, fmt.Errorf("Unknown rollover timezone '%s': %v", zone, err)
+		}
+	}
+	return r, nil
+}
+
+// Last returns the latest rollover not after now
+func (r Rollover) Last(now time.Time) time.Time {
+	loc := r.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	n := now.In(loc)
+	last := time.Date(n.Year(), n.Month(), n.Day(), r.Hour, r.Min, 0, 0, loc)
+	if last.After(n) {
+		last = last.AddDate(0, 0, -1)
+	}
+	return last
+}
+
+// LineupFresh tells if stored movies were retrieved after the latest
+// rollover and, when maxAge is not zero, at most maxAge ago
+func LineupFresh(movies []Data, now time.Time, rollover Rollover, maxAge time.Duration) bool {
+	var retrieved time.Time
+	for _, m := range movies {
+		if m.RetrievedAt.After(retrieved) {
+			retrieved = m.RetrievedAt
+		}
+	}
+	if retrieved.IsZero() {
+		// stored by older versions
+		return false
+	}
+	if maxAge > 0 && now.Sub(retrieved) > maxAge {
+		return false
+	}
+	return !retrieved.Before(rollover.Last(now))
+}
@@ -0,0 +1,141 @@
+package movie
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStaleFields(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	policy := map[string]time.Duration{
+		"MUBI rating": 48 * time.Hour,
+		"IMDB rating": 7 * 24 * time.Hour,
+	}
+
+	tests := []struct {
+		name    string
+		fetched map[string]time.Time
+		want    []string
+	}{
+		{"never fetched", nil, []string{"IMDB rating", "MUBI rating"}},
+		{"fresh", map[string]time.Time{
+			"MUBI rating": now.Add(-time.Hour),
+			"IMDB rating": now.Add(-time.Hour),
+		}, []string{}},
+		{"at max age", map[string]time.Time{
+			"MUBI rating": now.Add(-48 * time.Hour),
+			"IMDB rating": now.Add(-7 * 24 * time.Hour),
+		}, []string{}},
+		{"one stale", map[string]time.Time{
+			"MUBI rating": now.Add(-49 * time.Hour),
+			"IMDB rating": now.Add(-49 * time.Hour),
+		}, []string{"MUBI rating"}},
+		{"one missing", map[string]time.Time{
+			"MUBI rating": now,
+		}, []string{"IMDB rating"}},
+	}
+	for _, tt := range tests {
+		d := Data{Fetched: tt.fetched}
+		if got := d.StaleFields(policy, now); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: StaleFields() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshes(t *testing.T) {
+	tests := []struct {
+		name  string
+		stale []string
+		want  bool
+	}{
+		{"fetched anew", nil, true},
+		{"nothing stale", []string{}, false},
+		{"other field stale", []string{"MUBI rating"}, false},
+		{"field stale", []string{"IMDB rating"}, true},
+	}
+	for _, tt := range tests {
+		d := Data{Stale: tt.stale}
+		if got := d.Refreshes([]string{"IMDB rating", "IMDB id"}); got != tt.want {
+			t.Errorf("%s: Refreshes() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClearStale(t *testing.T) {
+	d := Data{Title: "Stalker", MubiRating: 4.2, ImdbRating: 8.1, ImdbID: "tt0079944",
+		Stale: []string{"MUBI rating", "IMDB rating"}}
+	d.ClearStale([]string{"IMDB rating", "IMDB id", "title"})
+	if d.ImdbRating != 0 {
+		t.Errorf("stale IMDB rating not cleared: %v", d.ImdbRating)
+	}
+	if d.Title != "Stalker" || d.ImdbID != "tt0079944" || d.MubiRating != 4.2 {
+		t.Errorf("fresh or other fields cleared: %+v", d)
+	}
+}
+
+func TestParseRollover(t *testing.T) {
+	tests := []struct {
+		clock, zone string
+		hour, min   int
+		ok          bool
+	}{
+		{"00:00", "", 0, 0, true},
+		{"15:30", "Europe/London", 15, 30, true},
+		{"7:05", "", 7, 5, true},
+		{"25:00", "", 0, 0, false},
+		{"noon", "", 0, 0, false},
+		{"00:00", "Europe/Nowhere", 0, 0, false},
+	}
+	for _, tt := range tests {
+		r, err := ParseRollover(tt.clock, tt.zone)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseRollover(%q, %q): error %v, want ok %v", tt.clock, tt.zone, err, tt.ok)
+			continue
+		}
+		if tt.ok && (r.Hour != tt.hour || r.Min != tt.min) {
+			t.Errorf("ParseRollover(%q, %q) = %d:%d, want %d:%d", tt.clock, tt.zone, r.Hour, r.Min, tt.hour, tt.min)
+		}
+	}
+}
+
+func TestLineupFresh(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 23:30 in London, 07:30 of the next day in Tokyo
+	now := time.Date(2026, 10, 19, 22, 30, 0, 0, time.UTC)
+	at := func(hour, min int) []Data {
+		return []Data{{RetrievedAt: time.Date(2026, 10, 19, hour, min, 0, 0, time.UTC)}}
+	}
+
+	tests := []struct {
+		name     string
+		movies   []Data
+		rollover Rollover
+		maxAge   time.Duration
+		want     bool
+	}{
+		{"no retrieval time", []Data{{}}, Rollover{0, 0, london}, 0, false},
+		{"no movies", nil, Rollover{0, 0, london}, 0, false},
+		{"after midnight in London", at(0, 0), Rollover{0, 0, london}, 0, true},
+		{"late evening in London", at(22, 0), Rollover{0, 0, london}, 0, true},
+		{"before midnight in Tokyo", at(14, 0), Rollover{0, 0, tokyo}, 0, false},
+		{"after midnight in Tokyo", at(15, 30), Rollover{0, 0, tokyo}, 0, true},
+		{"before afternoon rollover", at(12, 0), Rollover{14, 0, time.UTC}, 0, false},
+		{"after afternoon rollover", at(14, 0), Rollover{14, 0, time.UTC}, 0, true},
+		{"older than max age", at(0, 0), Rollover{0, 0, london}, 12 * time.Hour, false},
+		{"within max age", at(12, 0), Rollover{0, 0, london}, 12 * time.Hour, true},
+		{"latest retrieval counts", append(at(15, 0), at(16, 0)...), Rollover{0, 0, tokyo}, 0, true},
+	}
+	for _, tt := range tests {
+		if got := LineupFresh(tt.movies, now, tt.rollover, tt.maxAge); got != tt.want {
+			t.Errorf("%s: LineupFresh() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package movie
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// List is a multi-value field, like directors of co-directed movie
+type List []string
+
+// ParseList splits comma-joined values, like "Joel Coen, Ethan Coen"
+func ParseList(s string) List {
+	var l List
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// String joins values with commas
+func (l List) String() string {
+	return strings.Join(l, ", ")
+}
+
+// Contains checks if any value equals v, ignoring case
+func (l List) Contains(v string) bool {
+	for _, item := range l {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersects checks if lists share any value, ignoring case
+func (l List) Intersects(other List) bool {
+	for _, v := range other {
+		if l.Contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal checks if lists have the same values, in any order
+func (l List) Equal(other List) bool {
+	if len(l) != len(other) {
+		return false
+	}
+	for _, v := range other {
+		if !l.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// UnmarshalJSON reads list stored as array, or as comma-joined string
+// by older versions
+func (l *List) UnmarshalJSON(b []byte) error {
+	var items []string
+	if err := json.Unmarshal(b, &items); err == nil {
+		*l = items
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*l = ParseList(s)
+	return nil
+}
@@ -5,43 +5,79 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 	"time"
 
-	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/country"
 )
 
 const (
 	jsonFileName = "mubi.json"
+	imdbTitleURL = "https://www.imdb.com/title/%s/"
 
 	// time layout for data values
 	layout = "2006-1-2"
+
+	// DaysShowing is a number of days a movie stays available on MUBI
+	DaysShowing = 30
 )
 
 // JSONFilePath is a path mubi.json json file
 var (
 	JSONPath = ""
+	// Region is ISO 3166 alpha-2 code of MUBI region of the lineup, like
+	// "GB". Empty region stands for region MUBI picks by IP address
+	Region = ""
 )
 
 // Data represent movie data collected by parser
 type Data struct {
-	Title             string  `json:"title"`
-	Director          string  `json:"director"`
-	Country           string  `json:"country"`
-	Year              int     `json:"year,string"`
-	Genre             string  `json:"genre"`
-	Mins              int     `json:"mins,string"`
-	AltTitle          string  `json:"alt title"`
-	MubiLink          string  `json:"MUBI link"`
-	MubiRating        float64 `json:"MUBI rating,string"`
-	MubiRatingsNumber string  `json:"MUBI ratings num"`
-	ImdbRating        float64 `json:"IMDB rating,string"`
-	ImdbRatingsNumber string  `json:"IMDB ratings num"`
-	DaysToWatch       int     `json:"days,string"`
-	DateAppeared      string  `json:"appeared"`
+	Title             string    `json:"title"`
+	Directors         List      `json:"director"`
+	Countries         List      `json:"country"`
+	CountryCodes      List      `json:"country codes,omitempty"`
+	Year              int       `json:"year,string"`
+	Genres            List      `json:"genre"`
+	Mins              int       `json:"mins,string"`
+	AltTitle          string    `json:"alt title"`
+	MubiLink          string    `json:"MUBI link"`
+	MubiRating        float64   `json:"MUBI rating,string"`
+	MubiRatingsNumber Votes     `json:"MUBI ratings num"`
+	ImdbRating        float64   `json:"IMDB rating,string"`
+	ImdbRatingsNumber Votes     `json:"IMDB ratings num"`
+	ImdbID            string    `json:"IMDB id,omitempty"`
+	Region            string    `json:"region,omitempty"`
+	DaysToWatch       int       `json:"days,string"`
+	DateAppeared      string    `json:"appeared"`
+	LeavingAt         time.Time `json:"leaving at,omitzero"`
+	RetrievedAt       time.Time `json:"retrieved at,omitzero"`
+	Synopsis          string    `json:"synopsis,omitempty"`
+	OurTake           string    `json:"our take,omitempty"`
+	Cast              List      `json:"cast,omitempty"`
+	Languages         List      `json:"language,omitempty"`
+	Subtitles         List      `json:"subtitles,omitempty"`
+	ContentRating     string    `json:"content rating,omitempty"`
+	PosterURL         string    `json:"poster,omitempty"`
+	StillURL          string    `json:"still,omitempty"`
+	// Sources maps stored fields to extractors which produced them
+	Sources map[string]string `json:"sources,omitempty"`
+	// Failures are enrichment stages which failed in the last update
+	Failures []Failure `json:"failures,omitempty"`
+	// Fetched maps fields to when stages providing them last succeeded
+	Fetched map[string]time.Time `json:"fetched,omitempty"`
+
+	// Watched is personal state kept in watch log, not stored with movie
+	Watched bool `json:"-"`
+	// Score is recommendation score computed on demand, not stored with movie
+	Score float64 `json:"-"`
+	// Stale are fields to refresh in the current update, stages providing
+	// none of them are skipped. Nil for movies fetched anew
+	Stale []string `json:"-"`
 }
 
 func init() {
@@ -49,26 +85,56 @@ func init() {
 }
 
 func jsonfile() string {
-	return filepath.Join(JSONPath, jsonFileName)
-
-}
-
-// AbbrevCountry abbreviates names of selected countries
-func (d *Data) AbbrevCountry() {
-	switch d.Country {
-	case "United States":
-		d.Country = "USA"
-	case "United Kingdom":
-		d.Country = "UK"
-	case "Soviet Union":
-		d.Country = "USSR"
-	case "South Africa":
-		d.Country = "RSA"
-	case "Democratic Republic of the Congo":
-		d.Country = "DR Congo"
-	default:
-		return
+	return filepath.Join(JSONPath, RegionFileName(jsonFileName))
+}
+
+// RegionFileName returns name of data file of the lineup of Region, like
+// "mubi-gb.json" for "mubi.json"
+func RegionFileName(name string) string {
+	if Region == "" {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + strings.ToLower(Region) + ext
+}
+
+// SetCountries sets countries of movie, replacing names and abbreviations
+// known to ISO 3166 table with display names and setting their codes.
+// Unknown countries are kept as they are, with empty codes
+func (d *Data) SetCountries(names List) {
+	d.Countries, d.CountryCodes = nil, nil
+	for _, n := range names {
+		if c, ok := country.Lookup(n); ok {
+			d.Countries = append(d.Countries, c.Name)
+			d.CountryCodes = append(d.CountryCodes, c.Alpha3)
+		} else {
+			d.Countries = append(d.Countries, n)
+			d.CountryCodes = append(d.CountryCodes, "")
+		}
+	}
+}
+
+// FormatCountries returns countries joined with commas, each one in
+// display style of country package: name, code or flag
+func (d *Data) FormatCountries(style string) string {
+	var out []string
+	for _, c := range d.Countries {
+		out = append(out, country.Format(c, style))
+	}
+	if style == country.StyleFlag {
+		return strings.Join(out, " ")
+	}
+	return strings.Join(out, ", ")
+}
+
+// HasCountry checks if any of movie countries matches name or code
+func (d *Data) HasCountry(query string) bool {
+	for _, c := range d.Countries {
+		if country.Match(query, c) {
+			return true
+		}
 	}
+	return false
 }
 
 // Watch opens movie page in default browser
@@ -94,11 +160,17 @@ func WriteToJSON(movies []Data) error {
 		return err
 	}
 
-	err = ioutil.WriteFile(jsonfile(), out, 0666)
-	if err != nil {
+	return WriteFile(jsonfile(), out)
+}
+
+// WriteFile writes data to a temporary file renamed to path, so that
+// readers never see partially written file
+func WriteFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
 		return err
 	}
-	return nil
+	return os.Rename(tmp, path)
 }
 
 // ReadFromJSON reads json data from json file
@@ -111,42 +183,24 @@ func ReadFromJSON() ([]Data, error) {
 	if err := json.Unmarshal(out, &movies); err != nil {
 		return movies, err
 	}
-	return movies, nil
-}
-
-// FindByDay return movie based by day
-func FindByDay(day int, movies []Data) (Data, error) {
-	for _, m := range movies {
-		if m.DaysToWatch == day {
-			return m, nil
+	for i := range movies {
+		// countries abbreviated by older versions have no codes
+		if len(movies[i].CountryCodes) != len(movies[i].Countries) {
+			movies[i].SetCountries(movies[i].Countries)
+		}
+		// older versions kept leaving date only
+		if movies[i].LeavingAt.IsZero() {
+			if date, err := movies[i].ParseDateLeaving(); err == nil {
+				movies[i].LeavingAt = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+			}
 		}
 	}
-	return Data{}, fmt.Errorf("Movie not found")
-}
-
-// FromToday checks the current date, and date of newest movie appearance
-// from json - if matches, returns true. Currently not implemented
-// (always returns false)
-func FromToday(movies []Data) bool {
-
-	today := time.Now()
-	lastMovie, err := FindByDay(30, movies)
-	if err != nil {
-		debugging.Log().Printf("Could not find movie with 30 days left, %v\n", err)
-		return false
-	}
-
-	last, err := time.Parse(layout, lastMovie.DateAppeared)
-	if err != nil {
-		debugging.Log().Printf("Could not parse movie date, %v\n", err)
-		return false
-	}
-	return today.Year() == last.Year() && today.YearDay() == last.YearDay()
+	return movies, nil
 }
 
 // SetDateAppeared sets appearance date string in recognized layout
 func (d *Data) SetDateAppeared(retrieved time.Time) {
-	d.DateAppeared = retrieved.AddDate(0, 0, d.DaysToWatch-30).Format(layout)
+	d.DateAppeared = retrieved.AddDate(0, 0, d.DaysToWatch-DaysShowing).Format(layout)
 }
 
 // ParseDateAppeared returns date parsed from string
@@ -158,16 +212,70 @@ func (d *Data) ParseDateAppeared() (time.Time, error) {
 	return date, nil
 }
 
+// ParseDateLeaving returns date when movie leaves MUBI
+func (d *Data) ParseDateLeaving() (time.Time, error) {
+	date, err := d.ParseDateAppeared()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.AddDate(0, 0, DaysShowing), nil
+}
+
+// TotalVotes returns number of MUBI and IMDB votes together
+func (d *Data) TotalVotes() Votes {
+	return d.MubiRatingsNumber + d.ImdbRatingsNumber
+}
+
+// ImdbLink returns IMDB page address of movie, empty if IMDB id is unknown
+func (d *Data) ImdbLink() string {
+	if d.ImdbID == "" {
+		return ""
+	}
+	return fmt.Sprintf(imdbTitleURL, d.ImdbID)
+}
+
 // Find searches for movie in movie slice
 func Find(searched Data, in []Data) (Data, bool) {
 	for _, m := range in {
-		if searched.Title == m.Title && searched.Director == m.Director {
+		if searched.Title == m.Title && searched.Directors.Equal(m.Directors) {
 			return m, true
 		}
 	}
 	return Data{}, false
 }
 
+// SortKeys lists names of available sorting functions in display order
+var SortKeys = []string{"days", "mubi", "imdb", "mins", "year", "score", "votes"}
+
+// SortFunc returns sorting function identified by key
+func SortFunc(key string) (func([]Data), error) {
+	switch key {
+	case "days":
+		return SortByDays, nil
+	case "mubi":
+		return SortByMubi, nil
+	case "imdb":
+		return SortByImdb, nil
+	case "mins":
+		return SortByMins, nil
+	case "year":
+		return SortByYear, nil
+	case "score":
+		return SortByScore, nil
+	case "votes":
+		return SortByVotes, nil
+	default:
+		return nil, fmt.Errorf("Undefined sort parameter")
+	}
+}
+
+// Reverse reverses order of movies in slice
+func Reverse(movies []Data) {
+	for i, j := 0, len(movies)-1; i < j; i, j = i+1, j-1 {
+		movies[i], movies[j] = movies[j], movies[i]
+	}
+}
+
 // SortByDays sorts slice of movies by days to watch
 func SortByDays(movies []Data) {
 	sort.Slice(movies, func(i, j int) bool {
@@ -202,3 +310,17 @@ func SortByYear(movies []Data) {
 		return movies[i].Year > movies[j].Year
 	})
 }
+
+// SortByScore sorts slice of movies by recommendation score
+func SortByScore(movies []Data) {
+	sort.Slice(movies, func(i, j int) bool {
+		return movies[i].Score > movies[j].Score
+	})
+}
+
+// SortByVotes sorts slice of movies by total number of votes
+func SortByVotes(movies []Data) {
+	sort.Slice(movies, func(i, j int) bool {
+		return movies[i].TotalVotes() > movies[j].TotalVotes()
+	})
+}
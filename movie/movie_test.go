@@ -0,0 +1,49 @@
+package movie
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONTimes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "movie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { JSONPath = path }(JSONPath)
+	JSONPath = dir
+
+	retrieved := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	leaving := time.Date(2026, 10, 22, 0, 0, 0, 0, time.UTC)
+	movies := []Data{
+		{Title: "Stalker", DaysToWatch: 3, LeavingAt: leaving, RetrievedAt: retrieved},
+		// stored by versions without times
+		{Title: "Mirror", DaysToWatch: 2, DateAppeared: "2026-09-20"},
+	}
+	if err := WriteToJSON(movies); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(jsonfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "0001-01-01") {
+		t.Errorf("zero times written:\n%s", out)
+	}
+
+	got, err := ReadFromJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got[0].LeavingAt.Equal(leaving) || !got[0].RetrievedAt.Equal(retrieved) {
+		t.Errorf("times of %s: %v, %v", got[0].Title, got[0].LeavingAt, got[0].RetrievedAt)
+	}
+	want := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	if !got[1].LeavingAt.Equal(want) || !got[1].RetrievedAt.IsZero() {
+		t.Errorf("times of %s: %v, %v, want leaving at %v", got[1].Title, got[1].LeavingAt, got[1].RetrievedAt, want)
+	}
+}
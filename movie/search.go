@@ -0,0 +1,158 @@
+package movie
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// minimal score of fuzzy match to consider movie as found
+	matchThreshold = 0.6
+
+	// number of suggestions returned when nothing matches
+	maxSuggestions = 3
+)
+
+// Match is a movie found by Search with its match score in range 0-1
+type Match struct {
+	Movie Data
+	Score float64
+}
+
+// Search fuzzy-matches query against titles, alternative titles and
+// directors of movies. Returns matches sorted from the best one
+func Search(query string, movies []Data) []Match {
+	var matches []Match
+	for _, m := range rank(query, movies) {
+		if m.Score >= matchThreshold {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) > 0 && matches[0].Score == 1.0 {
+		// exact match makes the rest irrelevant
+		exact := matches[:1]
+		for _, m := range matches[1:] {
+			if m.Score == 1.0 {
+				exact = append(exact, m)
+			}
+		}
+		return exact
+	}
+	return matches
+}
+
+// Suggest returns titles of movies most similar to query
+func Suggest(query string, movies []Data) []string {
+	var titles []string
+	for _, m := range rank(query, movies) {
+		if len(titles) == maxSuggestions || m.Score == 0.0 {
+			break
+		}
+		titles = append(titles, m.Movie.Title)
+	}
+	return titles
+}
+
+func rank(query string, movies []Data) []Match {
+	query = normalize(query)
+	var matches []Match
+	for _, m := range movies {
+		best := 0.0
+		fields := append([]string{m.Title, m.AltTitle, m.Directors.String()}, m.Directors...)
+		for _, field := range fields {
+			if s := score(query, normalize(field)); s > best {
+				best = s
+			}
+		}
+		matches = append(matches, Match{m, best})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// score rates how well query matches text
+func score(query, text string) float64 {
+	switch {
+	case query == "" || text == "":
+		return 0.0
+	case query == text:
+		return 1.0
+	case strings.HasPrefix(text, query):
+		return 0.9
+	case strings.Contains(text, query):
+		return 0.8
+	case isSubsequence(query, text):
+		return 0.7
+	}
+	return similarity(query, text)
+}
+
+// isSubsequence checks if all query words are found in text in order
+func isSubsequence(query, text string) bool {
+	words := strings.Fields(query)
+	if len(words) < 2 {
+		return false
+	}
+	for _, w := range words {
+		i := strings.Index(text, w)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(w):]
+	}
+	return true
+}
+
+// similarity returns levenshtein distance based similarity of strings,
+// scaled to 0-0.7 range so typos never win over substring matches
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longer := len(ra)
+	if len(rb) > longer {
+		longer = len(rb)
+	}
+	return 0.7 * (1.0 - float64(levenshtein(ra, rb))/float64(longer))
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// normalize lowercases text, strips diacritics and punctuation
+func normalize(in string) string {
+	isMn := func(r rune) bool {
+		return unicode.Is(unicode.Mn, r)
+	}
+	t := transform.Chain(norm.NFD, transform.RemoveFunc(isMn), norm.NFC)
+	result, _, _ := transform.String(t, strings.ToLower(in))
+
+	result = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return r
+		}
+		return -1
+	}, result)
+	return strings.Join(strings.Fields(result), " ")
+}
@@ -0,0 +1,69 @@
+package movie
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SetSource records that field, named as in json, was produced by source
+func (d *Data) SetSource(field, source string) {
+	if d.Sources == nil {
+		d.Sources = map[string]string{}
+	}
+	d.Sources[field] = source
+}
+
+// Fill copies fields set in src to fields of d which are still empty,
+// recording source of each copied field. Fields set earlier take
+// precedence, so sources should be filled from the most reliable one
+func (d *Data) Fill(src Data, source string) {
+	dv := reflect.ValueOf(d).Elem()
+	sv := reflect.ValueOf(src)
+	t := dv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" || name == "sources" || name == "failures" || name == "fetched" {
+			continue
+		}
+		if dv.Field(i).IsZero() && !sv.Field(i).IsZero() {
+			dv.Field(i).Set(sv.Field(i))
+			d.SetSource(name, source)
+		}
+	}
+}
+
+// SourceFields returns fields produced by each source, sorted by name
+func (d *Data) SourceFields() map[string][]string {
+	fields := map[string][]string{}
+	for field, source := range d.Sources {
+		fields[source] = append(fields[source], field)
+	}
+	for _, f := range fields {
+		sort.Strings(f)
+	}
+	return fields
+}
+
+// IsField tells if name is a name of stored field, as in json
+func IsField(name string) bool {
+	t := reflect.TypeOf(Data{})
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonName returns name of stored field, empty for fields not stored
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
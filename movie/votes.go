@@ -0,0 +1,103 @@
+package movie
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// thousands separators used besides dots and commas
+const groupSeparators = "' \u00a0\u202f"
+
+// numeric part of vote count label, like "12,345 Ratings" or "1.2K"
+var votesPattern = regexp.MustCompile(`[0-9](?:[0-9.,' \x{00a0}\x{202f}]*[0-9])?[kKmM]?`)
+
+// Votes is a number of ratings a movie received
+type Votes int
+
+// ParseVotes reads number of votes from labels like "12,345", "12.345",
+// "1.2K" or "3M Ratings"
+func ParseVotes(s string) (Votes, error) {
+	num := strings.TrimSpace(votesPattern.FindString(s))
+	if num == "" {
+		return 0, fmt.Errorf("No number of votes found in '%s'", s)
+	}
+
+	multiplier := 1.0
+	switch num[len(num)-1] {
+	case 'k', 'K':
+		multiplier = 1e3
+	case 'm', 'M':
+		multiplier = 1e6
+	}
+
+	if multiplier > 1 {
+		// fraction part like in "1.2K" or "1,2K"
+		num = strings.Replace(stripSeparators(num[:len(num)-1], groupSeparators), ",", ".", 1)
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Could not parse number of votes '%s': %v", s, err)
+		}
+		return Votes(f*multiplier + 0.5), nil
+	}
+
+	i, err := strconv.Atoi(stripSeparators(num, ".,"+groupSeparators))
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse number of votes '%s': %v", s, err)
+	}
+	return Votes(i), nil
+}
+
+func stripSeparators(s, separators string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(separators, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// String formats votes with thousands separators
+func (v Votes) String() string {
+	s := strconv.Itoa(int(v))
+	if v < 0 {
+		return s
+	}
+	var sb strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// UnmarshalJSON reads votes stored as number, or as a string label by
+// older versions
+func (v *Votes) UnmarshalJSON(b []byte) error {
+	var i int
+	if err := json.Unmarshal(b, &i); err == nil {
+		*v = Votes(i)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if strings.TrimSpace(s) == "" {
+		*v = 0
+		return nil
+	}
+	parsed, err := ParseVotes(s)
+	if err != nil {
+		// unparsable labels stored by older versions are dropped
+		*v = 0
+		return nil
+	}
+	*v = parsed
+	return nil
+}
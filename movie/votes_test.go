@@ -0,0 +1,85 @@
+package movie
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseVotes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Votes
+	}{
+		{"12", 12},
+		{"12,345", 12345},
+		{"12.345", 12345},
+		{"1,234,567", 1234567},
+		{"12 345", 12345},
+		{"12\u00a0345", 12345},
+		{"12\u202f345", 12345},
+		{"12'345", 12345},
+		{"1.2K", 1200},
+		{"1,2K", 1200},
+		{"15k", 15000},
+		{"3M Ratings", 3000000},
+		{"2.5m", 2500000},
+		{"12,345 Ratings", 12345},
+		{"(1,024)", 1024},
+	}
+	for _, tt := range tests {
+		got, err := ParseVotes(tt.in)
+		if err != nil {
+			t.Errorf("ParseVotes(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseVotes(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	for _, s := range []string{"", "N/A", "Ratings"} {
+		if got, err := ParseVotes(s); err == nil {
+			t.Errorf("ParseVotes(%q) = %d, want error", s, got)
+		}
+	}
+}
+
+func TestVotesString(t *testing.T) {
+	tests := []struct {
+		in   Votes
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{1234567, "1,234,567"},
+		{-5, "-5"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Votes(%d).String() = %q, want %q", int(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestVotesUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Votes
+	}{
+		{`12345`, 12345},
+		{`"12,345"`, 12345},
+		{`"1.2K"`, 1200},
+		{`""`, 0},
+		{`"N/A"`, 0},
+	}
+	for _, tt := range tests {
+		var v Votes
+		if err := json.Unmarshal([]byte(tt.in), &v); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if v != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, v, tt.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package mubi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/llugin/mubi-parser/movie"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DaysLeft is a parsed days left label of a film
+type DaysLeft struct {
+	// Days film can still be watched, counting today
+	Days int
+	// LeavingAt is when film leaves MUBI
+	LeavingAt time.Time
+	// FilmOfTheDay is set for a film which appeared today
+	FilmOfTheDay bool
+}
+
+// labels, lowercased and without diacritics, of the film of the day in
+// languages MUBI is served in
+var filmOfTheDayLabels = []string{
+	"film of the day", "pelicula del dia", "film du jour", "film des tages",
+	"film del giorno", "filme do dia", "film van de dag", "film dnia",
+	"gunun filmi",
+}
+
+// labels of films leaving at the end of today
+var lastDayLabels = []string{
+	"midnight", "last chance", "last day", "today",
+	"medianoche", "ultimo dia", "ultima oportunidad", "hoy",
+	"minuit", "dernier jour", "derniere chance", "aujourd'hui",
+	"mitternacht", "letzter tag", "letzte chance", "heute",
+	"mezzanotte", "ultimo giorno", "ultima occasione", "oggi",
+	"meia-noite", "ultima chance", "hoje",
+	"middernacht", "laatste dag", "laatste kans", "vandaag",
+	"polnoc", "ostatni dzien", "ostatnia szansa", "dzisiaj",
+	"gece yarisi", "son gun", "son sans", "bugun",
+}
+
+// labels of films leaving at the end of tomorrow
+var tomorrowLabels = []string{
+	"tomorrow", "manana", "demain", "morgen", "domani", "amanha", "jutro",
+	"yarin",
+}
+
+// month names and abbreviations, a number next to one is a date rather
+// than days left
+var monthWords = []string{
+	"jan", "january", "feb", "february", "mar", "march", "apr", "april",
+	"may", "jun", "june", "jul", "july", "aug", "august", "sep", "sept",
+	"september", "oct", "october", "nov", "november", "dec", "december",
+	"ene", "enero", "febrero", "marzo", "abr", "abril", "mayo", "junio",
+	"julio", "ago", "agosto", "septiembre", "octubre", "noviembre", "dic",
+	"diciembre",
+	"janv", "janvier", "fevr", "fevrier", "mars", "avr", "avril", "mai",
+	"juin", "juil", "juillet", "aout", "septembre", "octobre", "novembre",
+	"decembre",
+	"januar", "februar", "marz", "juni", "juli", "okt", "oktober", "dez",
+	"dezember",
+	"gen", "gennaio", "febbraio", "aprile", "mag", "maggio", "giu", "giugno",
+	"lug", "luglio", "set", "settembre", "ott", "ottobre", "novembre",
+	"dicembre",
+	"janeiro", "fev", "fevereiro", "marco", "maio", "junho", "julho",
+	"setembro", "out", "outubro", "novembro", "dezembro",
+	"januari", "februari", "maart", "mrt", "mei", "augustus",
+	"sty", "stycznia", "lut", "lutego", "marca", "kwi", "kwietnia", "maja",
+	"cze", "czerwca", "lip", "lipca", "sie", "sierpnia", "wrz", "wrzesnia",
+	"paz", "pazdziernika", "lis", "listopada", "gru", "grudnia",
+	"ocak", "subat", "mart", "nisan", "mayis", "haziran", "temmuz",
+	"agustos", "eylul", "ekim", "kasim", "aralik",
+}
+
+// words of time units following the number in labels like "5 hours left"
+var unitWords = map[time.Duration][]string{
+	time.Minute: {"m", "min", "mins", "minute", "minutes", "minuto", "minutos",
+		"minuten", "minuti", "minuut", "minut", "minuty", "dakika"},
+	time.Hour: {"h", "hr", "hrs", "hour", "hours", "hora", "horas", "heure",
+		"heures", "stunde", "stunden", "ora", "ore", "uur", "godz", "godzina",
+		"godziny", "godzin", "saat"},
+	24 * time.Hour: {"d", "day", "days", "dia", "dias", "jour", "jours", "tag",
+		"tage", "tagen", "giorno", "giorni", "dag", "dagen", "dzien", "dni", "gun"},
+	7 * 24 * time.Hour: {"week", "weeks", "semana", "semanas", "semaine",
+		"semaines", "woche", "wochen", "settimana", "settimane", "weken",
+		"tydzien", "tygodnie", "tygodni", "hafta"},
+}
+
+// ParseDaysLeft parses localized days left label of a film tile relative to now
+func ParseDaysLeft(label string, now time.Time) (DaysLeft, error) {
+	text := foldLabel(label)
+	if text == "" {
+		return DaysLeft{}, fmt.Errorf("Empty days left label")
+	}
+	for _, l := range filmOfTheDayLabels {
+		if strings.Contains(text, l) {
+			left := newDaysLeft(now, startOfDay(now).AddDate(0, 0, movie.DaysShowing))
+			left.FilmOfTheDay = true
+			return left, nil
+		}
+	}
+
+	// a number is more exact than "last chance" it may come with
+	words := labelWords(text)
+	for i, w := range words {
+		n, err := strconv.Atoi(w)
+		if err != nil {
+			continue
+		}
+		unit := labelUnit(words[i+1:])
+		if unit == 0 {
+			// unit may precede the number, like "dni: 5"
+			unit = labelUnit(words[:i])
+		}
+		switch unit {
+		case 0:
+			// a bare number is days left, unless it is a part of a date
+			// like "29 Oct" or "2026-10-25"
+			if n < 1 || n > movie.DaysShowing || nextToDate(words, i) {
+				continue
+			}
+			return newDaysLeft(now, startOfDay(now).AddDate(0, 0, n)), nil
+		case 24 * time.Hour:
+			return newDaysLeft(now, startOfDay(now).AddDate(0, 0, n)), nil
+		case 7 * 24 * time.Hour:
+			return newDaysLeft(now, startOfDay(now).AddDate(0, 0, 7*n)), nil
+		default:
+			// hours are counted down on the last day only, even when it
+			// ends after local midnight
+			left := newDaysLeft(now, now.Add(time.Duration(n)*unit))
+			left.Days = 1
+			return left, nil
+		}
+	}
+
+	for _, l := range tomorrowLabels {
+		if strings.Contains(text, l) {
+			return newDaysLeft(now, startOfDay(now).AddDate(0, 0, 2)), nil
+		}
+	}
+	for _, l := range lastDayLabels {
+		if strings.Contains(text, l) {
+			return newDaysLeft(now, startOfDay(now).AddDate(0, 0, 1)), nil
+		}
+	}
+	return DaysLeft{}, fmt.Errorf("Unrecognized days left label '%s'", strings.TrimSpace(label))
+}
+
+// newDaysLeft returns days left for film leaving at given time, counting
+// today and the day it leaves on, unless it leaves right at midnight
+func newDaysLeft(now, leaving time.Time) DaysLeft {
+	last := startOfDay(leaving.Add(-time.Nanosecond))
+	days := int(math.Round(last.Sub(startOfDay(now)).Hours()/24)) + 1
+	if days < 1 {
+		days = 1
+	}
+	return DaysLeft{Days: days, LeavingAt: leaving}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// foldLabel lowercases label and strips diacritics and extra spaces
+func foldLabel(label string) string {
+	isMn := func(r rune) bool {
+		return unicode.Is(unicode.Mn, r)
+	}
+	label = strings.NewReplacer("ł", "l", "ı", "i", "’", "'").Replace(strings.ToLower(label))
+	t := transform.Chain(norm.NFD, transform.RemoveFunc(isMn), norm.NFC)
+	result, _, _ := transform.String(t, label)
+	return strings.Join(strings.Fields(result), " ")
+}
+
+// labelWords splits label into numbers and words, so "5h" gives "5", "h"
+func labelWords(text string) []string {
+	var words []string
+	var sb strings.Builder
+	kind := 0
+	flush := func() {
+		if sb.Len() > 0 {
+			words = append(words, sb.String())
+			sb.Reset()
+		}
+	}
+	for _, r := range text {
+		k := 0
+		switch {
+		case unicode.IsDigit(r):
+			k = 1
+		case unicode.IsLetter(r):
+			k = 2
+		}
+		if k != kind {
+			flush()
+			kind = k
+		}
+		if k != 0 {
+			sb.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// nextToDate tells if the number at i is next to a month or another number
+func nextToDate(words []string, i int) bool {
+	for _, j := range []int{i - 1, i + 1} {
+		if j < 0 || j >= len(words) {
+			continue
+		}
+		if _, err := strconv.Atoi(words[j]); err == nil {
+			return true
+		}
+		for _, m := range monthWords {
+			if words[j] == m {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// labelUnit returns time unit named by the first unit word, zero if none
+func labelUnit(words []string) time.Duration {
+	for _, w := range words {
+		for unit, names := range unitWords {
+			for _, name := range names {
+				if w == name {
+					return unit
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package mubi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDaysLeft(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, 19+d, 0, 0, 0, 0, time.UTC)
+	}
+	in := func(d time.Duration) time.Time {
+		return now.Add(d)
+	}
+
+	tests := []struct {
+		label        string
+		days         int
+		leavingAt    time.Time
+		filmOfTheDay bool
+	}{
+		{"12 days", 12, day(12), false},
+		{"1 day left", 1, day(1), false},
+		{"30 days", 30, day(30), false},
+		{"12", 12, day(12), false},
+		{"  7 DAYS  ", 7, day(7), false},
+		{"2 weeks", 14, day(14), false},
+		{"Expiring in 5 hours", 1, in(5 * time.Hour), false},
+		{"5h", 1, in(5 * time.Hour), false},
+		{"Expiring in 30 minutes", 1, in(30 * time.Minute), false},
+		{"Expiring tomorrow", 2, day(2), false},
+		{"Last chance", 1, day(1), false},
+		{"Last chance: 3 days", 3, day(3), false},
+		{"Leaving at midnight", 1, day(1), false},
+		{"Film of the day", 30, day(30), true},
+		{"Noch 12 Tage", 12, day(12), false},
+		{"Läuft morgen ab", 2, day(2), false},
+		{"Letzter Tag", 1, day(1), false},
+		{"Noch 3 Stunden", 1, in(3 * time.Hour), false},
+		{"Film des Tages", 30, day(30), true},
+		{"Quedan 3 días", 3, day(3), false},
+		{"Expira mañana", 2, day(2), false},
+		{"Último día", 1, day(1), false},
+		{"Expira en 4 horas", 1, in(4 * time.Hour), false},
+		{"Película del día", 30, day(30), true},
+		{"Plus que 7 jours", 7, day(7), false},
+		{"Expire demain", 2, day(2), false},
+		{"Dernier jour", 1, day(1), false},
+		{"Ancora 2 giorni", 2, day(2), false},
+		{"Scade domani", 2, day(2), false},
+		{"Film del giorno", 30, day(30), true},
+		{"Mais 6 dias", 6, day(6), false},
+		{"Expira amanhã", 2, day(2), false},
+		{"Nog 9 dagen", 9, day(9), false},
+		{"Laatste kans", 1, day(1), false},
+		{"Jeszcze 5 dni", 5, day(5), false},
+		{"dni: 5", 5, day(5), false},
+		{"Ostatni dzień", 1, day(1), false},
+		{"Film dnia", 30, day(30), true},
+		{"8 gün kaldı", 8, day(8), false},
+		{"Son gün", 1, day(1), false},
+		{"Günün filmi", 30, day(30), true},
+	}
+	for _, tt := range tests {
+		left, err := ParseDaysLeft(tt.label, now)
+		if err != nil {
+			t.Errorf("ParseDaysLeft(%q): %v", tt.label, err)
+			continue
+		}
+		if left.Days != tt.days || !left.LeavingAt.Equal(tt.leavingAt) || left.FilmOfTheDay != tt.filmOfTheDay {
+			t.Errorf("ParseDaysLeft(%q) = %d, %v, %v, want %d, %v, %v", tt.label,
+				left.Days, left.LeavingAt, left.FilmOfTheDay, tt.days, tt.leavingAt, tt.filmOfTheDay)
+		}
+	}
+}
+
+func TestParseDaysLeftRejects(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	for _, label := range []string{
+		"",
+		"   ",
+		"Expiring 29 Oct",
+		"Disponible jusqu'au 25 octobre",
+		"Available until 2026-10-25",
+		"Verfügbar bis 25.10.",
+		"45",
+		"0",
+		"Coming soon",
+	} {
+		if left, err := ParseDaysLeft(label, now); err == nil {
+			t.Errorf("ParseDaysLeft(%q) = %+v, want error", label, left)
+		}
+	}
+}
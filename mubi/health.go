@@ -0,0 +1,248 @@
+package mubi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// TileTolerance is how far number of films found on the showing page may
+// be from MaxMovies before markup is considered changed
+var TileTolerance = 5
+
+// DiagnosticsPath is a directory where pages of failed scrapes are saved
+var DiagnosticsPath = ""
+
+var (
+	// html of pages fetched since the showing page, keyed by url
+	pages   = map[string][]byte{}
+	pagesMu sync.Mutex
+)
+
+func resetPages() {
+	pagesMu.Lock()
+	pages = map[string][]byte{}
+	pagesMu.Unlock()
+}
+
+func keepPage(url string, body []byte) {
+	pagesMu.Lock()
+	pages[url] = body
+	pagesMu.Unlock()
+}
+
+// Problem is a single scraped value which does not look right
+type Problem struct {
+	Film string
+	Link string
+	Msg  string
+}
+
+func (p Problem) String() string {
+	if p.Film == "" {
+		return p.Msg
+	}
+	return fmt.Sprintf("%s: %s", p.Film, p.Msg)
+}
+
+// Check is a named validation of scraped films with problems it found
+type Check struct {
+	Name     string
+	Problems []Problem
+}
+
+// OK tells if check found no problems
+func (c Check) OK() bool {
+	return len(c.Problems) == 0
+}
+
+// Validate checks films read from the showing page and films with details
+// read from their pages, which may be a subset of them
+func Validate(basic, detailed []movie.Data) []Check {
+	count := Check{Name: "tile count"}
+	if n := len(basic); n < MaxMovies-TileTolerance || n > MaxMovies+TileTolerance {
+		count.Problems = append(count.Problems, Problem{
+			Msg: fmt.Sprintf("found %d films, expected about %d", n, MaxMovies)})
+	}
+
+	fields := Check{Name: "showing page fields"}
+	days := Check{Name: "days left"}
+	for i, m := range basic {
+		film := filmName(i, m)
+		problem := func(msg string) Problem {
+			return Problem{Film: film, Link: m.MubiLink, Msg: msg}
+		}
+		for _, f := range []struct {
+			name  string
+			empty bool
+		}{
+			{"title", m.Title == ""},
+			{"director", len(m.Directors) == 0},
+			{"year", m.Year == 0},
+			{"link", m.MubiLink == "" || m.MubiLink == baseURL},
+		} {
+			if f.empty {
+				fields.Problems = append(fields.Problems, problem("no "+f.name))
+			}
+		}
+		if m.DaysToWatch < 1 || m.DaysToWatch > movie.DaysShowing {
+			days.Problems = append(days.Problems,
+				problem(fmt.Sprintf("%d days left, expected 1-%d", m.DaysToWatch, movie.DaysShowing)))
+		}
+	}
+
+	// single film may lack some details, markup changed when most do
+	details := Check{Name: "film page fields"}
+	missing := map[string][]Problem{}
+	for i, m := range detailed {
+		for _, f := range []struct {
+			name  string
+			empty bool
+		}{
+			{"MUBI rating", m.MubiRating == 0.0},
+			{"mins", m.Mins == 0},
+			{"genre", len(m.Genres) == 0},
+		} {
+			if f.empty {
+				missing[f.name] = append(missing[f.name],
+					Problem{Film: filmName(i, m), Link: m.MubiLink, Msg: "no " + f.name})
+			}
+		}
+	}
+	for _, name := range []string{"MUBI rating", "mins", "genre"} {
+		if problems := missing[name]; len(problems)*2 > len(detailed) {
+			details.Problems = append(details.Problems, problems...)
+		}
+	}
+	return []Check{count, fields, days, details}
+}
+
+// filmName names i-th film in problems, also when its title is missing
+func filmName(i int, m movie.Data) string {
+	if m.Title == "" {
+		return fmt.Sprintf("film #%d", i+1)
+	}
+	return m.Title
+}
+
+// LayoutError reports failed checks of scraped films
+type LayoutError struct {
+	Checks []Check
+	// Dir is where diagnostics were saved, empty if they were not
+	Dir string
+}
+
+// Failed returns checks which found problems
+func Failed(checks []Check) []Check {
+	var failed []Check
+	for _, c := range checks {
+		if !c.OK() {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+func (e *LayoutError) Error() string {
+	return "MUBI layout changed\n" + e.Report()
+}
+
+// Report describes failed checks, listing at most few problems of each
+func (e *LayoutError) Report() string {
+	const maxListed = 5
+	var sb strings.Builder
+	for _, c := range Failed(e.Checks) {
+		noun := "problems"
+		if len(c.Problems) == 1 {
+			noun = "problem"
+		}
+		fmt.Fprintf(&sb, "  %s: %d %s\n", c.Name, len(c.Problems), noun)
+		for i, p := range c.Problems {
+			if i == maxListed {
+				fmt.Fprintf(&sb, "    ... and %d more\n", len(c.Problems)-maxListed)
+				break
+			}
+			fmt.Fprintf(&sb, "    %s\n", p)
+		}
+	}
+	if e.Dir != "" {
+		fmt.Fprintf(&sb, "Pages saved to %s\n", e.Dir)
+	}
+	sb.WriteString("Check selectors with 'mubicmd doctor' and patch the selector profile")
+	return sb.String()
+}
+
+// CheckScrape validates scraped films and returns LayoutError with saved
+// diagnostics when any check fails
+func CheckScrape(basic, detailed []movie.Data) error {
+	checks := Validate(basic, detailed)
+	if len(Failed(checks)) == 0 {
+		return nil
+	}
+	e := &LayoutError{Checks: checks}
+	dir, err := SaveDiagnostics(checks)
+	if err != nil {
+		return fmt.Errorf("%v\nCould not save diagnostics: %v", e, err)
+	}
+	e.Dir = dir
+	return e
+}
+
+// SaveDiagnostics saves report of checks, html of the showing page and of
+// film pages with problems to a new directory in DiagnosticsPath
+func SaveDiagnostics(checks []Check) (string, error) {
+	dir := filepath.Join(DiagnosticsPath, time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	var report strings.Builder
+	links := map[string]bool{regionShowingURL(movie.Region): true}
+	for _, c := range checks {
+		status := "OK"
+		if !c.OK() {
+			status = "FAILED"
+		}
+		fmt.Fprintf(&report, "%s: %s\n", c.Name, status)
+		for _, p := range c.Problems {
+			fmt.Fprintf(&report, "  %s\n", p)
+			if p.Link != "" {
+				links[p.Link] = true
+			}
+		}
+	}
+	fmt.Fprintf(&report, "\nselector profile:\n%+v\n", CurrentProfile())
+	if err := ioutil.WriteFile(filepath.Join(dir, "report.txt"), []byte(report.String()), 0666); err != nil {
+		return dir, err
+	}
+
+	pagesMu.Lock()
+	defer pagesMu.Unlock()
+	for link := range links {
+		if body, ok := pages[link]; ok {
+			if err := ioutil.WriteFile(filepath.Join(dir, pageFileName(link)), body, 0666); err != nil {
+				return dir, err
+			}
+		}
+	}
+	return dir, nil
+}
+
+// pageFileName returns name of file for page at link, like "stalker.html"
+func pageFileName(link string) string {
+	if strings.HasSuffix(link, "/showing") {
+		return "showing.html"
+	}
+	name := path.Base(strings.TrimRight(link, "/"))
+	if name == "" || name == "." || name == "/" {
+		name = "page"
+	}
+	return name + ".html"
+}
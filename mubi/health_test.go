@@ -0,0 +1,68 @@
+package mubi
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func lineup(n int) []movie.Data {
+	var movies []movie.Data
+	for i := 0; i < n; i++ {
+		movies = append(movies, movie.Data{
+			Title:       fmt.Sprintf("Film %d", i),
+			Directors:   movie.List{"Director"},
+			Year:        2000,
+			MubiLink:    fmt.Sprintf("https://mubi.com/films/film-%d", i),
+			DaysToWatch: i%movie.DaysShowing + 1,
+			MubiRating:  4,
+			Mins:        90,
+			Genres:      movie.List{"Drama"},
+		})
+	}
+	return movies
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func([]movie.Data) []movie.Data
+		failed []string
+	}{
+		{"valid", func(m []movie.Data) []movie.Data { return m }, nil},
+		{"few tiles", func(m []movie.Data) []movie.Data { return m[:10] }, []string{"tile count"}},
+		{"no title", func(m []movie.Data) []movie.Data {
+			m[3].Title = ""
+			return m
+		}, []string{"showing page fields"}},
+		{"days above showing period", func(m []movie.Data) []movie.Data {
+			m[0].DaysToWatch = movie.DaysShowing + 1
+			return m
+		}, []string{"days left"}},
+		{"no days", func(m []movie.Data) []movie.Data {
+			m[0].DaysToWatch = 0
+			return m
+		}, []string{"days left"}},
+		{"few films without rating", func(m []movie.Data) []movie.Data {
+			m[0].MubiRating = 0
+			return m
+		}, nil},
+		{"most films without genre", func(m []movie.Data) []movie.Data {
+			for i := range m {
+				m[i].Genres = nil
+			}
+			return m
+		}, []string{"film page fields"}},
+	}
+	for _, tt := range tests {
+		movies := tt.change(lineup(MaxMovies))
+		var failed []string
+		for _, c := range Failed(Validate(movies, movies)) {
+			failed = append(failed, c.Name)
+		}
+		if fmt.Sprint(failed) != fmt.Sprint(tt.failed) {
+			t.Errorf("%s: failed checks %v, want %v", tt.name, failed, tt.failed)
+		}
+	}
+}
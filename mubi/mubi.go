@@ -1,15 +1,17 @@
 package mubi
 
 import (
+	"bytes"
 	"fmt"
-	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/fetch"
 	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/workers"
 )
 
 const (
@@ -18,23 +20,22 @@ const (
 	showingURL = "https://mubi.com/showing"
 	baseURL    = "https://mubi.com"
 
-	// mubi goquery selection queries
-	selMovie          = ".full-width-tile--now-showing, .showing-page-hero-tile"
-	selTitle          = ".full-width-tile__title, .showing-page-hero-tile__title"
-	selLink           = ".full-width-tile__link, .showing-page-hero-tile__link"
-	selDaysToWatch    = ".showing-page-hero-tile__fotd-label, .full-width-tile__days-left"
-	selDirector       = "[itemprop=name]"
-	selCountryAndYear = ".now-showing-tile-director-year__year-country"
-	selGenre          = ".film-show__genres"
-	selAltTitle       = ".film-show__titles__title-alt"
-	selRating         = ".average-rating__overall"
-	selRatingsNumber  = ".average-rating__total"
-	selMins           = "[itemprop=duration]"
+	// Host is MUBI host name requests are rate limited by
+	Host = "mubi.com"
 )
 
+// StageDetails is name of the stage reading film pages
+const StageDetails = "MUBI details"
+
+// detailFields are fields, named as in json, read from film pages
+var detailFields = []string{
+	"MUBI rating", "MUBI ratings num", "mins", "genre", "synopsis", "our take",
+	"cast", "language", "subtitles", "content rating", "poster", "still",
+}
+
 var (
-	// Sleep - sleep between HTTP reqeuests to mubi site in seconds
-	Sleep         = 3
+	// Workers - number of film pages fetched at once
+	Workers       = 4
 	retrievalDate time.Time
 )
 
@@ -43,15 +44,23 @@ var (
 func SendMoviesWithBasicData(done <-chan struct{}) (<-chan movie.Data, error) {
 	moviesChan := make(chan movie.Data, MaxMovies)
 
-	s, err := getSelectionFromWebPage()
+	doc, err := getDocumentFromWebPage()
 	if err != nil {
 		return moviesChan, err
 	}
 
+	p := CurrentProfile()
+	s := doc.Find(p.Movie)
+	if s.Length() == 0 {
+		debugging.Log().Println("No film tiles found, reading films from __NEXT_DATA__")
+		go sendFromNextData(done, moviesChan, nextData(doc))
+		return moviesChan, nil
+	}
+
 	go func() {
 		defer close(moviesChan)
 		s.Each(func(i int, s *goquery.Selection) {
-			movie, err := queryBasicData(s)
+			movie, err := queryBasicData(s, p)
 			if err != nil {
 				debugging.Log().Println(err)
 			} else {
@@ -66,123 +75,188 @@ func SendMoviesWithBasicData(done <-chan struct{}) (<-chan movie.Data, error) {
 	return moviesChan, nil
 }
 
-//SendMoviesDetails returns channel with movies with detailed data
-func SendMoviesDetails(done <-chan struct{}, in <-chan movie.Data) <-chan movie.Data {
-	var doc *goquery.Document
-	var resp *http.Response
-	var err error
-
-	out := make(chan movie.Data, MaxMovies)
-
-	go func() {
-		defer close(out)
-		for md := range in {
-			time.Sleep(time.Duration(Sleep) * time.Second)
-
-			resp, err = http.Get(md.MubiLink)
-			debugging.Log().Printf("getting %s\n", md.MubiLink)
-			if err != nil {
-				debugging.Log().Println(err)
-				goto Send
-			}
-			defer resp.Body.Close()
+func sendFromNextData(done <-chan struct{}, out chan<- movie.Data, films []nextFilm) {
+	defer close(out)
+	for i, f := range films {
+		if i == MaxMovies {
+			break
+		}
+		var d, md movie.Data
+		f.apply(&d, retrievalDate)
+		md.Fill(d, SourceNextData)
+		md.SetDateAppeared(retrievalDate)
+		md.Region = movie.Region
+		md.RetrievedAt = retrievalDate
+		select {
+		case out <- md:
+		case <-done:
+			return
+		}
+	}
+}
 
-			doc, err = goquery.NewDocumentFromReader(resp.Body)
-			if err != nil {
-				debugging.Log().Println(err)
-				goto Send
-			}
+//SendMoviesDetails returns channel with movies with detailed data, in
+// order they were received. Movies with no stale detail fields are passed on
+func SendMoviesDetails(done <-chan struct{}, in <-chan movie.Data) <-chan movie.Data {
+	return workers.Map(done, in, Workers, StageDetails, func(md movie.Data) movie.Data {
+		if !md.Refreshes(detailFields) {
+			return md
+		}
+		debugging.Log().Printf("getting %s\n", md.MubiLink)
+		if doc, err := getDocument(md.MubiLink, md.Region); err == nil {
+			md.ClearStale(detailFields)
 			acquireDetailsFromDocument(&md, doc)
-
-		Send:
-			select {
-			case out <- md:
-			case <-done:
-				return
-			}
+			md.SetFetched(detailFields, time.Now())
+		} else {
+			debugging.Log().Println(err)
+			md.Fail(StageDetails, err, detailFields...)
 		}
-	}()
-	return out
+		return md
+	})
 }
 
 func acquireDetailsFromDocument(m *movie.Data, doc *goquery.Document) {
-	ratingStr := strings.TrimSpace(doc.Find(selRating).Text())
+	for _, e := range extractors {
+		d := movie.Data{MubiLink: m.MubiLink}
+		e.extract(&d, doc)
+		m.Fill(d, e.name)
+	}
+}
+
+// fromSelectors reads film page details with goquery selectors
+func fromSelectors(m *movie.Data, doc *goquery.Document) {
+	p := CurrentProfile()
+	ratingStr := strings.TrimSpace(doc.Find(p.Rating).Text())
 	if f, err := strconv.ParseFloat(ratingStr, 32); err == nil {
 		m.MubiRating = f
 	} else {
 		debugging.Log().Println(err)
 		m.MubiRating = 0.0
 	}
-	m.Genre = strings.TrimSpace(doc.Find(selGenre).Text())
-	m.AltTitle = strings.TrimSpace(doc.Find(selAltTitle).Text())
-	minsStr := strings.TrimSpace(doc.Find(selMins).Text())
+	m.Genres = movie.ParseList(doc.Find(p.Genre).Text())
+	m.AltTitle = strings.TrimSpace(doc.Find(p.AltTitle).Text())
+	minsStr := strings.TrimSpace(doc.Find(p.Mins).Text())
 	if i, err := strconv.Atoi(minsStr); err == nil {
 		m.Mins = i
 	} else {
 		debugging.Log().Println(err)
 	}
-	raw := doc.Find(selRatingsNumber).Text()
-	m.MubiRatingsNumber = strings.TrimSpace(strings.Trim(raw, "Ratings\n"))
+	raw := doc.Find(p.RatingsNumber).Text()
+	if votes, err := movie.ParseVotes(raw); err == nil {
+		m.MubiRatingsNumber = votes
+	} else {
+		debugging.Log().Println(err)
+		m.MubiRatingsNumber = 0
+	}
 
+	m.Synopsis = text(doc.Selection, p.Synopsis)
+	m.OurTake = text(doc.Selection, p.OurTake)
+	m.ContentRating = text(doc.Selection, p.ContentRating)
+	m.Cast = list(doc.Selection, p.Cast)
+	m.Languages = list(doc.Selection, p.Languages)
+	m.Subtitles = list(doc.Selection, p.Subtitles)
+	m.PosterURL = imageURL(doc.Selection, p.Poster)
+	m.StillURL = imageURL(doc.Selection, p.Still)
+}
+
+// text returns whitespace-collapsed text of the first element matching sel
+func text(s *goquery.Selection, sel string) string {
+	return strings.Join(strings.Fields(s.Find(sel).First().Text()), " ")
+}
+
+// list returns values of all elements matching sel, each of which may hold
+// comma-joined values prefixed with a label, like "Subtitles: English, French"
+func list(s *goquery.Selection, sel string) movie.List {
+	var l movie.List
+	s.Find(sel).Each(func(i int, s *goquery.Selection) {
+		t := s.Text()
+		if n := strings.Index(t, ":"); n >= 0 {
+			t = t[n+1:]
+		}
+		for _, v := range movie.ParseList(t) {
+			if !l.Contains(v) {
+				l = append(l, v)
+			}
+		}
+	})
+	return l
 }
 
-func queryBasicData(s *goquery.Selection) (movie.Data, error) {
+// imageURL returns absolute URL of the first image matching sel, taken
+// from src attribute of img elements or content of meta tags
+func imageURL(s *goquery.Selection, sel string) string {
+	img := s.Find(sel).First()
+	u, ok := img.Attr("src")
+	if !ok {
+		u, _ = img.Attr("content")
+	}
+	if strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") {
+		u = baseURL + u
+	}
+	return u
+}
+
+func queryBasicData(s *goquery.Selection, p Profile) (movie.Data, error) {
 	var md movie.Data
 	var err error
 
-	md.Title = s.Find(selTitle).Text()
-	md.Director = s.Find(selDirector).Text()
+	md.Title = s.Find(p.Title).Text()
+	s.Find(p.Director).Each(func(i int, s *goquery.Selection) {
+		md.Directors = append(md.Directors, movie.ParseList(s.Text())...)
+	})
 
-	countryAndYear := strings.Split(s.Find(selCountryAndYear).Text(), ", ")
-	md.Country = countryAndYear[0]
-	md.AbbrevCountry()
-	year, err := strconv.Atoi(countryAndYear[1])
-	if err == nil {
-		md.Year = year
-	} else {
-		debugging.Log().Println(err)
+	// countries followed by year, like "France, Germany, 2019"
+	countryAndYear := movie.ParseList(s.Find(p.CountryAndYear).Text())
+	if n := len(countryAndYear); n > 0 {
+		md.SetCountries(countryAndYear[:n-1])
+		year, err := strconv.Atoi(countryAndYear[n-1])
+		if err == nil {
+			md.Year = year
+		} else {
+			debugging.Log().Println(err)
+		}
 	}
 
-	daysToWatchStr := s.Find(selDaysToWatch).Text()
-	if daysToWatch, err := parseDaysToWatch(daysToWatchStr); err == nil {
-		md.DaysToWatch = daysToWatch
+	if left, err := ParseDaysLeft(s.Find(p.DaysToWatch).Text(), retrievalDate); err == nil {
+		md.DaysToWatch = left.Days
+		md.LeavingAt = left.LeavingAt
 	} else {
 		debugging.Log().Println(err)
 	}
 
-	link, exists := s.Find(selLink).Attr("href")
+	link, exists := s.Find(p.Link).Attr("href")
 	md.MubiLink = baseURL + link
 	if !exists {
 		err = fmt.Errorf("%v: link for movie details could not be found", md.Title)
 	}
 
 	md.SetDateAppeared(retrievalDate)
+	md.Region = movie.Region
 
-	return md, err
+	var out movie.Data
+	out.Fill(md, SourceCSS)
+	out.RetrievedAt = retrievalDate
+	return out, err
 }
 
-func parseDaysToWatch(text string) (int, error) {
-	text = strings.TrimSpace(text)
-	if text == "Film of the day" {
-		return MaxMovies, nil
-	} else if text == "Expiring at midnight" {
-		return 1, nil
-	} else {
-		return strconv.Atoi(strings.Split(text, " ")[0])
-	}
+func getDocumentFromWebPage() (*goquery.Document, error) {
+	resetPages()
+	doc, err := getDocument(regionShowingURL(movie.Region), movie.Region)
+	retrievalDate = time.Now()
+	return doc, err
 }
 
-func getSelectionFromWebPage() (*goquery.Selection, error) {
-	resp, err := http.Get(showingURL)
+// getDocument fetches page at url in locale of region, keeping its html
+// for diagnostics
+func getDocument(url, region string) (*goquery.Document, error) {
+	req, err := newRequest(url, region)
 	if err != nil {
 		return nil, err
 	}
-	retrievalDate = time.Now()
-	defer resp.Body.Close()
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, err := fetch.Get(req, Host)
 	if err != nil {
 		return nil, err
 	}
-
-	return doc.Find(selMovie), nil
+	keepPage(url, body)
+	return goquery.NewDocumentFromReader(bytes.NewReader(body))
 }
@@ -0,0 +1,134 @@
+package mubi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultProfileName names the built-in selector profile
+const DefaultProfileName = "default"
+
+// Profile holds goquery selectors of MUBI pages. Selectors of the showing
+// page are searched within each film tile
+type Profile struct {
+	// showing page
+	Movie          string `json:"movie"`
+	Title          string `json:"title"`
+	Link           string `json:"link"`
+	DaysToWatch    string `json:"daysToWatch"`
+	Director       string `json:"director"`
+	CountryAndYear string `json:"countryAndYear"`
+
+	// film page
+	Genre         string `json:"genre"`
+	AltTitle      string `json:"altTitle"`
+	Rating        string `json:"rating"`
+	RatingsNumber string `json:"ratingsNumber"`
+	Mins          string `json:"mins"`
+	Synopsis      string `json:"synopsis"`
+	OurTake       string `json:"ourTake"`
+	Cast          string `json:"cast"`
+	Languages     string `json:"languages"`
+	Subtitles     string `json:"subtitles"`
+	ContentRating string `json:"contentRating"`
+	Poster        string `json:"poster"`
+	Still         string `json:"still"`
+}
+
+// DefaultProfile is the built-in selector profile
+var DefaultProfile = Profile{
+	Movie:          ".full-width-tile--now-showing, .showing-page-hero-tile",
+	Title:          ".full-width-tile__title, .showing-page-hero-tile__title",
+	Link:           ".full-width-tile__link, .showing-page-hero-tile__link",
+	DaysToWatch:    ".showing-page-hero-tile__fotd-label, .full-width-tile__days-left",
+	Director:       "[itemprop=name]",
+	CountryAndYear: ".now-showing-tile-director-year__year-country",
+
+	Genre:         ".film-show__genres",
+	AltTitle:      ".film-show__titles__title-alt",
+	Rating:        ".average-rating__overall",
+	RatingsNumber: ".average-rating__total",
+	Mins:          "[itemprop=duration]",
+	Synopsis:      ".film-show__descriptions__synopsis, [itemprop=description]",
+	OurTake:       ".film-show__our-take__text",
+	Cast:          "[itemprop=actor] [itemprop=name]",
+	Languages:     ".film-show__languages, [itemprop=inLanguage]",
+	Subtitles:     ".film-show__subtitles",
+	ContentRating: "[itemprop=contentRating]",
+	Poster:        ".film-show__poster img, meta[property='og:image']",
+	Still:         ".film-show__still img, .film-show__hero img",
+}
+
+var (
+	// profile is the selector profile in use
+	profile   = DefaultProfile
+	profileMu sync.RWMutex
+)
+
+// CurrentProfile returns the selector profile in use
+func CurrentProfile() Profile {
+	profileMu.RLock()
+	defer profileMu.RUnlock()
+	return profile
+}
+
+// ReadProfiles reads selector profiles from json file, an object of
+// profiles keyed by name. Selectors missing in a profile are taken from
+// the built-in one, which is available as "default" unless file overrides
+// it. Missing file results in the built-in profile only
+func ReadProfiles(path string) (map[string]Profile, error) {
+	profiles := map[string]Profile{DefaultProfileName: DefaultProfile}
+	if path == "" {
+		return profiles, nil
+	}
+	out, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return profiles, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return profiles, fmt.Errorf("%s: %v", path, err)
+	}
+	for name, r := range raw {
+		p := DefaultProfile
+		dec := json.NewDecoder(bytes.NewReader(r))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&p); err != nil {
+			return profiles, fmt.Errorf("%s: profile %s: %v", path, name, err)
+		}
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// UseProfile switches selectors to profile of given name read from file.
+// Films fetched afterwards are scraped with the new selectors
+func UseProfile(path, name string) error {
+	profiles, err := ReadProfiles(path)
+	if err != nil {
+		return err
+	}
+	p, ok := profiles[name]
+	if !ok {
+		var names []string
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("Unknown selector profile '%s', available: %s", name, strings.Join(names, ", "))
+	}
+	profileMu.Lock()
+	profile = p
+	profileMu.Unlock()
+	return nil
+}
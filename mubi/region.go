@@ -0,0 +1,75 @@
+package mubi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// languages MUBI serves pages in for regions, English is used for others
+var regionLanguages = map[string]string{
+	"AT": "de", "CH": "de", "DE": "de",
+	"BE": "fr", "FR": "fr",
+	"AR": "es", "CL": "es", "CO": "es", "ES": "es", "MX": "es", "PE": "es",
+	"IT": "it",
+	"BR": "pt", "PT": "pt",
+	"NL": "nl",
+	"PL": "pl",
+	"TR": "tr",
+}
+
+func regionLanguage(region string) string {
+	if lang, ok := regionLanguages[strings.ToUpper(region)]; ok {
+		return lang
+	}
+	return "en"
+}
+
+// regionShowingURL returns address of the showing page of region, like
+// "https://mubi.com/de/de/showing". Empty region leaves it to MUBI
+func regionShowingURL(region string) string {
+	if region == "" {
+		return showingURL
+	}
+	return fmt.Sprintf("%s/%s/%s/showing", baseURL, regionLanguage(region), strings.ToLower(region))
+}
+
+// newRequest returns GET request of url with locale of region set in
+// Accept-Language header and lang/country cookies
+func newRequest(url, region string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		return req, nil
+	}
+	lang := regionLanguage(region)
+	locale := lang + "-" + strings.ToUpper(region)
+	req.Header.Set("Accept-Language", fmt.Sprintf("%s,%s;q=0.9,en;q=0.5", locale, lang))
+	req.AddCookie(&http.Cookie{Name: "lang", Value: lang})
+	req.AddCookie(&http.Cookie{Name: "country", Value: strings.ToUpper(region)})
+	return req, nil
+}
+
+// timezones of regions MUBI lineups change in, the most populous one for
+// regions spanning several
+var regionTimezones = map[string]string{
+	"GB": "Europe/London", "IE": "Europe/Dublin",
+	"US": "America/New_York", "CA": "America/Toronto",
+	"AT": "Europe/Vienna", "CH": "Europe/Zurich", "DE": "Europe/Berlin",
+	"BE": "Europe/Brussels", "FR": "Europe/Paris", "NL": "Europe/Amsterdam",
+	"ES": "Europe/Madrid", "IT": "Europe/Rome", "PT": "Europe/Lisbon",
+	"PL": "Europe/Warsaw", "TR": "Europe/Istanbul",
+	"AR": "America/Argentina/Buenos_Aires", "BR": "America/Sao_Paulo",
+	"CL": "America/Santiago", "CO": "America/Bogota", "MX": "America/Mexico_City",
+	"PE": "America/Lima",
+	"IN": "Asia/Kolkata", "JP": "Asia/Tokyo", "MY": "Asia/Kuala_Lumpur",
+	"SG": "Asia/Singapore", "AU": "Australia/Sydney", "NZ": "Pacific/Auckland",
+}
+
+// RegionTimezone returns IANA timezone of region, empty for regions not
+// known and when MUBI picks the region
+func RegionTimezone(region string) string {
+	return regionTimezones[strings.ToUpper(region)]
+}
@@ -0,0 +1,319 @@
+package mubi
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// names of extractors recorded as sources of movie fields
+const (
+	SourceJSONLD   = "json-ld"
+	SourceNextData = "next-data"
+	SourceCSS      = "css"
+)
+
+const (
+	selJSONLD   = "script[type='application/ld+json']"
+	selNextData = "script#__NEXT_DATA__"
+)
+
+// ISO 8601 durations used by schema.org, like "PT2H41M"
+var durationRe = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?`)
+
+// extractor reads movie details from film page
+type extractor struct {
+	name    string
+	extract func(m *movie.Data, doc *goquery.Document)
+}
+
+// extractors of film page details, from the most reliable one. Later ones
+// only fill fields the former did not find
+var extractors = []extractor{
+	{SourceJSONLD, fromJSONLD},
+	{SourceNextData, fromNextData},
+	{SourceCSS, fromSelectors},
+}
+
+// ldValues is a schema.org property, which may be given as a text, number,
+// object with a name or url, or an array of those
+type ldValues []string
+
+func (v *ldValues) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		var items []ldValues
+		if err := json.Unmarshal(b, &items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			*v = append(*v, item...)
+		}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = append(*v, strings.TrimSpace(s))
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err == nil {
+		*v = append(*v, n.String())
+		return nil
+	}
+	var obj struct {
+		Name  string `json:"name"`
+		URL   string `json:"url"`
+		Value string `json:"@value"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	for _, s := range []string{obj.Name, obj.URL, obj.Value} {
+		if s != "" {
+			*v = append(*v, strings.TrimSpace(s))
+			break
+		}
+	}
+	return nil
+}
+
+func (v ldValues) first() string {
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+// ldMovie is a schema.org Movie object
+type ldMovie struct {
+	Type            ldValues `json:"@type"`
+	Name            ldValues `json:"name"`
+	AlternateName   ldValues `json:"alternateName"`
+	Description     ldValues `json:"description"`
+	Image           ldValues `json:"image"`
+	Duration        ldValues `json:"duration"`
+	Genre           ldValues `json:"genre"`
+	Director        ldValues `json:"director"`
+	Actor           ldValues `json:"actor"`
+	CountryOfOrigin ldValues `json:"countryOfOrigin"`
+	InLanguage      ldValues `json:"inLanguage"`
+	ContentRating   ldValues `json:"contentRating"`
+	DateCreated     ldValues `json:"dateCreated"`
+	AggregateRating struct {
+		RatingValue ldValues `json:"ratingValue"`
+		RatingCount ldValues `json:"ratingCount"`
+		BestRating  ldValues `json:"bestRating"`
+	} `json:"aggregateRating"`
+	Graph []json.RawMessage `json:"@graph"`
+}
+
+// fromJSONLD reads schema.org Movie object embedded in the page
+func fromJSONLD(m *movie.Data, doc *goquery.Document) {
+	doc.Find(selJSONLD).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		ld, ok := findLDMovie([]byte(s.Text()))
+		if ok {
+			ld.apply(m)
+		}
+		return !ok
+	})
+}
+
+// findLDMovie finds Movie object in JSON-LD block, which may hold a single
+// object, an array of objects or a @graph of them
+func findLDMovie(b []byte) (ldMovie, bool) {
+	b = bytes.TrimSpace(b)
+	var objects []json.RawMessage
+	if len(b) > 0 && b[0] == '[' {
+		if err := json.Unmarshal(b, &objects); err != nil {
+			debugging.Log().Printf("Could not parse JSON-LD: %v\n", err)
+			return ldMovie{}, false
+		}
+	} else {
+		objects = []json.RawMessage{b}
+	}
+
+	for _, obj := range objects {
+		var ld ldMovie
+		if err := json.Unmarshal(obj, &ld); err != nil {
+			debugging.Log().Printf("Could not parse JSON-LD: %v\n", err)
+			continue
+		}
+		for _, t := range ld.Type {
+			if t == "Movie" {
+				return ld, true
+			}
+		}
+		for _, g := range ld.Graph {
+			if found, ok := findLDMovie(g); ok {
+				return found, true
+			}
+		}
+	}
+	return ldMovie{}, false
+}
+
+func (ld ldMovie) apply(m *movie.Data) {
+	m.Title = ld.Name.first()
+	m.AltTitle = ld.AlternateName.first()
+	m.Synopsis = ld.Description.first()
+	m.PosterURL = ld.Image.first()
+	m.Genres = movie.List(ld.Genre)
+	m.Directors = movie.List(ld.Director)
+	m.Cast = movie.List(ld.Actor)
+	m.Languages = movie.List(ld.InLanguage)
+	m.ContentRating = ld.ContentRating.first()
+	if len(ld.CountryOfOrigin) > 0 {
+		m.SetCountries(movie.List(ld.CountryOfOrigin))
+	}
+	if match := durationRe.FindStringSubmatch(ld.Duration.first()); match != nil {
+		hours, _ := strconv.Atoi(match[1])
+		mins, _ := strconv.Atoi(match[2])
+		m.Mins = hours*60 + mins
+	}
+	if date := ld.DateCreated.first(); len(date) >= 4 {
+		m.Year, _ = strconv.Atoi(date[:4])
+	}
+
+	rating := ld.AggregateRating
+	if f, err := strconv.ParseFloat(rating.RatingValue.first(), 64); err == nil {
+		// MUBI ratings are kept in 0-5 scale of the rating widget
+		if best, err := strconv.ParseFloat(rating.BestRating.first(), 64); err == nil && best > 0 {
+			f = f * 5 / best
+		}
+		m.MubiRating = math.Round(f*10) / 10
+	}
+	if votes, err := movie.ParseVotes(rating.RatingCount.first()); err == nil {
+		m.MubiRatingsNumber = votes
+	}
+}
+
+// nextFilm is a film object of MUBI API, embedded by Next.js in the page
+type nextFilm struct {
+	Title         string `json:"title"`
+	OriginalTitle string `json:"original_title"`
+	Year          int    `json:"year"`
+	Duration      int    `json:"duration"`
+	WebURL        string `json:"web_url"`
+	Directors     []struct {
+		Name string `json:"name"`
+	} `json:"directors"`
+	Genres           []string `json:"genres"`
+	Countries        []string `json:"historic_countries"`
+	AverageRating    float64  `json:"average_rating"`
+	NumberOfRatings  int      `json:"number_of_ratings"`
+	ShortSynopsis    string   `json:"short_synopsis"`
+	DefaultEditorial string   `json:"default_editorial"`
+	StillURL         string   `json:"still_url"`
+	ContentRating    struct {
+		Label string `json:"label"`
+	} `json:"content_rating"`
+	Consumable struct {
+		ExpiresAt string `json:"expires_at"`
+	} `json:"consumable"`
+}
+
+// nextData returns film objects found in Next.js data of the page, in
+// order of their keys
+func nextData(doc *goquery.Document) []nextFilm {
+	raw := doc.Find(selNextData).First().Text()
+	if raw == "" {
+		return nil
+	}
+	var tree interface{}
+	if err := json.Unmarshal([]byte(raw), &tree); err != nil {
+		debugging.Log().Printf("Could not parse __NEXT_DATA__: %v\n", err)
+		return nil
+	}
+
+	var films []nextFilm
+	seen := map[string]bool{}
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch n := node.(type) {
+		case []interface{}:
+			for _, v := range n {
+				walk(v)
+			}
+		case map[string]interface{}:
+			if isNextFilm(n) {
+				var f nextFilm
+				b, _ := json.Marshal(n)
+				if err := json.Unmarshal(b, &f); err == nil && !seen[f.WebURL] {
+					seen[f.WebURL] = true
+					films = append(films, f)
+				}
+				return
+			}
+			keys := make([]string, 0, len(n))
+			for k := range n {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(n[k])
+			}
+		}
+	}
+	walk(tree)
+	return films
+}
+
+// isNextFilm tells film objects from other objects of Next.js data
+func isNextFilm(n map[string]interface{}) bool {
+	for _, key := range []string{"title", "directors", "web_url"} {
+		if _, ok := n[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// fromNextData reads details of film with matching link from Next.js data
+func fromNextData(m *movie.Data, doc *goquery.Document) {
+	for _, f := range nextData(doc) {
+		if f.WebURL == m.MubiLink {
+			f.apply(m, retrievalDate)
+			return
+		}
+	}
+}
+
+// apply sets fields of m from f, counting days left from retrieval of
+// the lineup
+func (f nextFilm) apply(m *movie.Data, retrieved time.Time) {
+	m.Title = f.Title
+	if f.OriginalTitle != f.Title {
+		m.AltTitle = f.OriginalTitle
+	}
+	m.Year = f.Year
+	m.Mins = f.Duration
+	m.MubiLink = f.WebURL
+	for _, d := range f.Directors {
+		m.Directors = append(m.Directors, d.Name)
+	}
+	m.Genres = movie.List(f.Genres)
+	if len(f.Countries) > 0 {
+		m.SetCountries(movie.List(f.Countries))
+	}
+	m.MubiRating = math.Round(f.AverageRating*10) / 10
+	m.MubiRatingsNumber = movie.Votes(f.NumberOfRatings)
+	m.Synopsis = f.ShortSynopsis
+	m.OurTake = f.DefaultEditorial
+	m.StillURL = f.StillURL
+	m.ContentRating = f.ContentRating.Label
+	if expires, err := time.Parse(time.RFC3339, f.Consumable.ExpiresAt); err == nil {
+		left := newDaysLeft(retrieved, expires.Local())
+		m.DaysToWatch, m.LeavingAt = left.Days, left.LeavingAt
+	}
+}
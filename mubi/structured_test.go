@@ -0,0 +1,161 @@
+package mubi
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "mubi")
+	if err != nil {
+		panic(err)
+	}
+	debugging.InitLogger(dir, false)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func page(t *testing.T, head string) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + head + "</head><body></body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+const ldStalker = `{"@context":"https://schema.org","@type":"Movie","name":"Stalker",
+	"alternateName":"Сталкер","description":"A guide leads two men through the Zone.",
+	"image":{"@type":"ImageObject","url":"https://images.mubicdn.net/stalker.jpg"},
+	"duration":"PT2H41M","genre":["Drama","Sci-Fi"],
+	"director":[{"@type":"Person","name":"Andrei Tarkovsky"}],
+	"actor":[{"@type":"Person","name":"Alisa Freyndlikh"},{"@type":"Person","name":"Aleksandr Kaydanovskiy"}],
+	"countryOfOrigin":{"@type":"Country","name":"USSR"},"inLanguage":"Russian",
+	"contentRating":"PG","dateCreated":"1979-05-25",
+	"aggregateRating":{"@type":"AggregateRating","ratingValue":9.0,"bestRating":10,"ratingCount":"12,345"}}`
+
+func TestFromJSONLD(t *testing.T) {
+	want := movie.Data{
+		Title: "Stalker", AltTitle: "Сталкер", Synopsis: "A guide leads two men through the Zone.",
+		PosterURL: "https://images.mubicdn.net/stalker.jpg", Mins: 161, Year: 1979,
+		Genres: movie.List{"Drama", "Sci-Fi"}, Directors: movie.List{"Andrei Tarkovsky"},
+		Cast:      movie.List{"Alisa Freyndlikh", "Aleksandr Kaydanovskiy"},
+		Languages: movie.List{"Russian"}, ContentRating: "PG",
+		MubiRating: 4.5, MubiRatingsNumber: 12345,
+	}
+	want.SetCountries(movie.List{"USSR"})
+
+	tests := []struct {
+		name string
+		head string
+	}{
+		{"single object", `<script type="application/ld+json">` + ldStalker + `</script>`},
+		{"array", `<script type="application/ld+json">[{"@type":"WebSite","name":"MUBI"},` + ldStalker + `]</script>`},
+		{"graph", `<script type="application/ld+json">{"@context":"https://schema.org","@graph":[` +
+			`{"@type":"BreadcrumbList","name":"Films"},` + ldStalker + `]}</script>`},
+		{"after other blocks", `<script type="application/ld+json">{"@type":"Organization","name":"MUBI"}</script>` +
+			`<script type="application/ld+json">not json</script>` +
+			`<script type="application/ld+json">` + ldStalker + `</script>`},
+	}
+	for _, tt := range tests {
+		var m movie.Data
+		fromJSONLD(&m, page(t, tt.head))
+		if !reflect.DeepEqual(m, want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, m, want)
+		}
+	}
+}
+
+func TestFromJSONLDWithoutMovie(t *testing.T) {
+	for _, head := range []string{
+		"",
+		`<script type="application/ld+json">{"@type":"Organization","name":"MUBI"}</script>`,
+		`<script type="application/ld+json">{broken</script>`,
+	} {
+		var m movie.Data
+		fromJSONLD(&m, page(t, head))
+		if !reflect.DeepEqual(m, movie.Data{}) {
+			t.Errorf("%q: got %+v, want nothing", head, m)
+		}
+	}
+}
+
+const nextDataPage = `<script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{
+	"initialState":{"films":{"b":{"title":"Mirror","original_title":"Zerkalo","year":1975,"duration":107,
+		"web_url":"https://mubi.com/films/mirror","directors":[{"name":"Andrei Tarkovsky"}],
+		"genres":["Drama"],"historic_countries":["USSR"],"average_rating":4.44,"number_of_ratings":30000,
+		"short_synopsis":"A dying man remembers.","default_editorial":"A masterpiece.",
+		"still_url":"https://images.mubicdn.net/mirror.jpg","content_rating":{"label":"12"}},
+	"a":{"title":"Stalker","original_title":"Stalker","year":1979,"duration":161,
+		"web_url":"https://mubi.com/films/stalker","directors":[{"name":"Andrei Tarkovsky"}],
+		"genres":["Drama","Sci-Fi"],"average_rating":4.5,"number_of_ratings":50000}},
+	"user":{"title":"not a film"}}}}}</script>`
+
+func TestNextData(t *testing.T) {
+	films := nextData(page(t, nextDataPage))
+	var titles []string
+	for _, f := range films {
+		titles = append(titles, f.Title)
+	}
+	if !reflect.DeepEqual(titles, []string{"Stalker", "Mirror"}) {
+		t.Errorf("films %v, want films in order of their keys", titles)
+	}
+
+	if films := nextData(page(t, `<script id="__NEXT_DATA__">{broken</script>`)); films != nil {
+		t.Errorf("films of broken data: %+v", films)
+	}
+}
+
+func TestFromNextData(t *testing.T) {
+	want := movie.Data{
+		Title: "Mirror", AltTitle: "Zerkalo", Year: 1975, Mins: 107,
+		MubiLink: "https://mubi.com/films/mirror", Directors: movie.List{"Andrei Tarkovsky"},
+		Genres: movie.List{"Drama"}, MubiRating: 4.4, MubiRatingsNumber: 30000,
+		Synopsis: "A dying man remembers.", OurTake: "A masterpiece.",
+		StillURL: "https://images.mubicdn.net/mirror.jpg", ContentRating: "12",
+	}
+	want.SetCountries(movie.List{"USSR"})
+
+	m := movie.Data{MubiLink: "https://mubi.com/films/mirror"}
+	fromNextData(&m, page(t, nextDataPage))
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %+v, want %+v", m, want)
+	}
+
+	// same title and original title give no alternative title
+	m = movie.Data{MubiLink: "https://mubi.com/films/stalker"}
+	fromNextData(&m, page(t, nextDataPage))
+	if m.Title != "Stalker" || m.AltTitle != "" || m.Mins != 161 {
+		t.Errorf("got %+v, want Stalker without alternative title", m)
+	}
+
+	for _, link := range []string{"https://mubi.com/films/solaris", ""} {
+		m = movie.Data{MubiLink: link}
+		fromNextData(&m, page(t, nextDataPage))
+		if m.Title != "" {
+			t.Errorf("film %q missing in data filled from another one: %+v", link, m)
+		}
+	}
+}
+
+func TestNextFilmDaysLeft(t *testing.T) {
+	// retrieved just before midnight, days left do not change with the date
+	retrieved := time.Date(2026, 10, 19, 23, 59, 0, 0, time.Local)
+	leaving := time.Date(2026, 10, 22, 0, 0, 0, 0, time.Local)
+	f := nextFilm{Title: "Stalker"}
+	f.Consumable.ExpiresAt = leaving.Format(time.RFC3339)
+
+	var m movie.Data
+	f.apply(&m, retrieved)
+	if m.DaysToWatch != 3 || !m.LeavingAt.Equal(leaving) {
+		t.Errorf("days left %d, leaving at %v, want 3 and %v", m.DaysToWatch, m.LeavingAt, leaving)
+	}
+}
@@ -1,118 +1,181 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+	// rollover timezones are known without system timezone database
+	_ "time/tzdata"
 
+	"github.com/llugin/mubi-parser/country"
 	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/fetch"
+	"github.com/llugin/mubi-parser/history"
 	"github.com/llugin/mubi-parser/imdb"
 	"github.com/llugin/mubi-parser/movie"
 	"github.com/llugin/mubi-parser/mubi"
 	"github.com/llugin/mubi-parser/parser"
 	"github.com/llugin/mubi-parser/printer"
+	"github.com/llugin/mubi-parser/score"
+	"github.com/llugin/mubi-parser/watchlist"
+	"github.com/llugin/mubi-parser/watchlog"
 )
 
-const jsonFileName = "mubi.json"
-
-type config struct {
-	OMDBKey  string `json:"OMDBKey"`
-	DataPath string `json:"DataPath"`
-	LogPath  string `json:"LogPath"`
-}
-
-func readConfig() (config, error) {
-	ex, err := os.Executable()
-	if err != nil {
-		return config{}, err
-	}
-	cwd := filepath.Dir(ex)
-	configPath := filepath.Join(cwd, "mubiconf.json")
-	if _, err = os.Stat(configPath); os.IsNotExist(err) {
-		// Set default values
-		return config{"", cwd, cwd}, nil
-	}
-
-	out, err := ioutil.ReadFile(configPath)
-	if err != nil {
-		return config{}, err
-	}
-	var c config
-	err = json.Unmarshal(out, &c)
-	return c, err
-}
+// exit codes of runs which found MUBI markup changed and of runs which
+// could not get all data of some films
+const (
+	exitLayoutChanged    = 3
+	exitEnrichmentFailed = 4
+)
 
 func main() {
 	log.SetFlags(log.Lshortfile)
 
-	flagCached := flag.Bool("cached", false, "Read only data from mubi.json file - no web connection are made")
-	flagStderrLog := flag.Bool("stderr-debug", false, "Print debug info to stderr")
-	flagMubiSleep := flag.Int("mubi-sleep", 3, "Sleep between mubi HTTP requests in seconds")
-	flagImdbSleep := flag.Int("imdb-sleep", 200, "Sleep between OMDB API calls in milliseconds")
-	flagNoColor := flag.Bool("no-color", false, "Disable color output")
-	flagRefresh := flag.Bool("refresh", false, "Refresh all data, not only new movies")
-	flagWatch := flag.Int("watch", -1, "Watch picked movie identified by 'Days' value")
-	flagMaxLen := flag.Int("max-len", 32, "Max output table column length. Value equal or less than zero stands for unlimited length.")
-	sv := sortValue{movie.SortByDays, false}
-	flag.Var(&sv, "sort", "Sort by: [mubi|imdb|days|mins|year], default: days. Add '-' at argument end to reverse order")
-
+	commands := newCommands()
+	globals.register(flag.CommandLine)
+	confFlags.register(flag.CommandLine)
+	flag.Usage = func() { usage(commands) }
 	flag.Parse()
-	conf, err := readConfig()
+
+	name := defaultCommand
+	args := flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := findCommand(commands, name)
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "Unknown command: %s\n\n", name)
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd.flags.Parse(args)
+
+	conf, err := readConfig(confFlags)
 	if err != nil {
 		log.Fatal(err)
 	}
+	for _, dir := range []string{conf.DataPath, conf.LogPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	movie.JSONPath = conf.DataPath
+	history.JSONPath = conf.DataPath
+	watchlog.JSONPath = conf.DataPath
+	watchlist.JSONPath = conf.DataPath
+	mubi.DiagnosticsPath = filepath.Join(conf.DataPath, "diagnostics")
+	watchlist.Threshold = conf.WatchlistDays
+	score.Prefs.Genres = conf.ScoreGenres
+	score.Prefs.Countries = conf.ScoreCountries
+	score.Prefs.Decades = conf.ScoreDecades
+	score.Prefs.MaxMins = conf.ScoreMaxMins
+	score.Prefs.Urgency = conf.ScoreUrgency
 	imdb.APIKey = conf.OMDBKey
-	debugging.InitLogger(conf.LogPath, *flagStderrLog)
-
-	imdb.Sleep = *flagImdbSleep
-	mubi.Sleep = *flagMubiSleep
+	debugging.InitLogger(conf.LogPath, globals.stderrLog)
 
-	start := time.Now()
+	switch globals.countryStyle {
+	case country.StyleName, country.StyleCode, country.StyleFlag:
+		printer.CountryStyle = globals.countryStyle
+	default:
+		log.Fatalf("Undefined country style: %s", globals.countryStyle)
+	}
 
-	var movies []movie.Data
-	justWatch := *flagWatch != -1
+	if regions, err = parseRegions(globals.region); err != nil {
+		log.Fatal(err)
+	}
+	if len(regions) > 1 && !cmd.multiRegion {
+		log.Fatalf("Command %s accepts a single region", cmd.name)
+	}
+	if len(regions) > 0 {
+		movie.Region = regions[0]
+	}
 
-	if *flagCached || justWatch {
-		movies, err = movie.ReadFromJSON()
-	} else {
-		movies, err = parser.GetMovies(*flagRefresh)
+	if globals.mubiSleep > 0 {
+		conf.MubiRate = 1 / float64(globals.mubiSleep)
 	}
-	if err != nil {
+	if globals.imdbSleep > 0 {
+		conf.OMDBRate = 1000 / float64(globals.imdbSleep)
+	}
+	mubi.Workers = conf.MubiWorkers
+	imdb.Workers = conf.OMDBWorkers
+	fetch.Jitter = conf.RateJitter
+	if parser.RefreshAfter, err = conf.refreshAfter(); err != nil {
 		log.Fatal(err)
 	}
-
-	if justWatch {
-		if err := watch(movies, *flagWatch); err != nil {
-			log.Fatal(err)
+	if _, err := movie.ParseRollover(conf.RolloverTime, conf.RolloverZone); err != nil {
+		log.Fatal(err)
+	}
+	parser.RolloverTime = conf.RolloverTime
+	parser.RolloverZone = conf.RolloverZone
+	parser.MaxAge = time.Duration(conf.CacheMaxHours * float64(time.Hour))
+	if conf.FetchRetries < 0 {
+		log.Fatalf("FetchRetries must not be negative, got %d", conf.FetchRetries)
+	}
+	fetch.Retries = conf.FetchRetries
+	fetch.Timeout = time.Duration(conf.FetchTimeout * float64(time.Second))
+	// bursts of one request per worker, limited by rate afterwards
+	fetch.SetLimit(mubi.Host, conf.MubiRate, conf.MubiWorkers)
+	fetch.SetLimit(imdb.Host, conf.OMDBRate, conf.OMDBWorkers)
+
+	if err := cmd.run(cmd.flags.Args(), conf); err != nil {
+		var layoutErr *mubi.LayoutError
+		if errors.As(err, &layoutErr) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitLayoutChanged)
 		}
-	} else {
-		sv.sort(movies)
-		printer.PrintTable(movies, *flagNoColor, *flagMaxLen)
-
-		if err = movie.WriteToJSON(movies); err != nil {
-			log.Fatal(err)
+		if enrichmentFailed(err) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitEnrichmentFailed)
 		}
-
-		log.Printf("Total time: %0.f s\n", time.Since(start).Seconds())
+		log.Fatal(err)
 	}
 }
 
-func watch(movies []movie.Data, day int) error {
-	m, err := movie.FindByDay(day, movies)
+// pick returns film identified by listing index or fuzzy matched title.
+// When several films match, user is asked to pick one
+func pick(movies []movie.Data, query string) (movie.Data, error) {
+	if i, err := strconv.Atoi(query); err == nil && i >= 1 && i <= len(movies) {
+		return movies[i-1], nil
+	}
+
+	matches := movie.Search(query, movies)
+	switch len(matches) {
+	case 0:
+		msg := fmt.Sprintf("No film matches '%s'", query)
+		if suggestions := movie.Suggest(query, movies); len(suggestions) > 0 {
+			msg += ", did you mean:\n  " + strings.Join(suggestions, "\n  ")
+		}
+		return movie.Data{}, errors.New(msg)
+	case 1:
+		return matches[0].Movie, nil
+	}
+
+	fmt.Printf("Several films match '%s':\n", query)
+	for i, m := range matches {
+		fmt.Printf("  %d) %s (%s, %d)\n", i+1, m.Movie.Title, m.Movie.Directors, m.Movie.Year)
+	}
+	fmt.Printf("Pick film [1-%d]: ", len(matches))
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
 	if err != nil {
-		return err
+		return movie.Data{}, fmt.Errorf("No film picked: %v", err)
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || i < 1 || i > len(matches) {
+		return movie.Data{}, fmt.Errorf("Invalid choice '%s'", strings.TrimSpace(line))
 	}
-	return m.Watch()
+	return matches[i-1].Movie, nil
 }
 
 type sortValue struct {
+	key         string
 	sortingFunc func([]movie.Data)
 	reversed    bool
 }
@@ -122,11 +185,10 @@ func (s *sortValue) String() string {
 }
 
 func (s *sortValue) sort(m []movie.Data) {
+	score.Apply(m)
 	s.sortingFunc(m)
 	if s.reversed {
-		for i, j := 0, len(m)-1; i < j; i, j = i+1, j-1 {
-			m[i], m[j] = m[j], m[i]
-		}
+		movie.Reverse(m)
 	}
 }
 
@@ -138,20 +200,12 @@ func (s *sortValue) Set(val string) error {
 		s.reversed = false
 	}
 
-	switch val {
-	case "days":
-		s.sortingFunc = movie.SortByDays
-	case "mubi":
-		s.sortingFunc = movie.SortByMubi
-	case "imdb":
-		s.sortingFunc = movie.SortByImdb
-	case "mins":
-		s.sortingFunc = movie.SortByMins
-	case "year":
-		s.sortingFunc = movie.SortByYear
-	default:
-		return fmt.Errorf("Undefined sort parameter")
+	f, err := movie.SortFunc(val)
+	if err != nil {
+		return err
 	}
+	s.key = val
+	s.sortingFunc = f
 
 	return nil
 }
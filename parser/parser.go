@@ -1,22 +1,72 @@
 package parser
 
 import (
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/fetch"
 	"github.com/llugin/mubi-parser/imdb"
 	"github.com/llugin/mubi-parser/movie"
 	"github.com/llugin/mubi-parser/mubi"
+	"github.com/llugin/mubi-parser/watchlist"
+	"github.com/llugin/mubi-parser/workers"
 )
 
-// GetMovies reads movie data from the web
+// Alerts - watchlist alerts flagged by the last GetMovies call
+var Alerts []watchlist.Alert
+
+// RefreshAfter maps fields, named as in json, to age after which cached
+// films have them fetched again. Fields not listed are fetched only once
+var RefreshAfter = map[string]time.Duration{}
+
+var (
+	// RolloverTime - "15:04" time of day MUBI lineup changes at
+	RolloverTime = "00:00"
+	// RolloverZone - IANA timezone of RolloverTime, timezone of the region
+	// when empty
+	RolloverZone = ""
+	// MaxAge - age after which stored lineup is fetched again even if MUBI
+	// lineup has not changed since, zero for no limit
+	MaxAge time.Duration
+)
+
+// EnrichmentError is returned by GetMovies, along with all movies, when
+// details or ratings of some of them could not be obtained
+type EnrichmentError struct {
+	// Failed are movies with failed stages listed in their Failures
+	Failed []movie.Data
+}
+
+func (e *EnrichmentError) Error() string {
+	return fmt.Sprintf("Could not get all data of %d film(s)", len(e.Failed))
+}
+
+// GetMovies reads movie data from the web and flags watchlisted movies
+// in Alerts. Films scraped from the web are validated, mubi.LayoutError is
+// returned when they suggest MUBI markup changed. EnrichmentError is
+// returned when only stages of some movies failed
 func GetMovies(refresh bool) ([]movie.Data, error) {
 
 	done := make(chan struct{})
 	defer close(done)
+	// state of the previous update
+	Alerts = nil
+	atomic.StoreInt64(&imdb.APICount, 0)
+	workers.Reset()
+	fetch.ResetFailures()
+
+	// lineup from before this update, nil if there is none
+	previous, err := movie.ReadFromJSON()
+	if err != nil {
+		previous = nil
+	}
 
 	if !refresh {
 		if movies, ok := cacheSuccess(); ok {
+			flagWatchlisted(movies, previous)
 			return movies, nil
 		}
 	}
@@ -34,22 +84,66 @@ func GetMovies(refresh bool) ([]movie.Data, error) {
 	for m := range merge(done, out, cached) {
 		movies = append(movies, m)
 	}
-	debugging.Log().Printf("OMDB API called %v times\n", imdb.APICount)
+	debugging.Log().Printf("OMDB API called %v times\n", atomic.LoadInt64(&imdb.APICount))
+	// pages which could not be fetched say nothing about layout
+	var fetched, failed []movie.Data
+	for _, m := range movies {
+		if !m.FailedStage(mubi.StageDetails) {
+			fetched = append(fetched, m)
+		}
+		if len(m.Failures) > 0 {
+			failed = append(failed, m)
+		}
+	}
+	if err := mubi.CheckScrape(movies, fetched); err != nil {
+		return movies, err
+	}
+	flagWatchlisted(movies, previous)
+	if len(failed) > 0 {
+		return movies, &EnrichmentError{Failed: failed}
+	}
 	return movies, nil
 }
 
+func flagWatchlisted(movies, previous []movie.Data) {
+	Alerts = nil
+	wl, err := watchlist.Read()
+	if err != nil {
+		debugging.Log().Printf("Could not read watchlist: %v\n", err)
+		return
+	}
+	Alerts = wl.Check(movies, previous)
+}
+
 func cacheSuccess() ([]movie.Data, bool) {
 	movies, err := movie.ReadFromJSON()
 	if err != nil {
 		debugging.Log().Printf("Could not read cached data json: %s\n", err)
 		return nil, false
 	}
-	if movie.FromToday(movies) {
+	for _, m := range movies {
+		if len(m.Failures) > 0 {
+			// retry stages which failed
+			return nil, false
+		}
+	}
+	zone := RolloverZone
+	if zone == "" {
+		zone = mubi.RegionTimezone(movie.Region)
+	}
+	rollover, err := movie.ParseRollover(RolloverTime, zone)
+	if err != nil {
+		debugging.Log().Println(err)
+		return nil, false
+	}
+	if movie.LineupFresh(movies, time.Now(), rollover, MaxAge) {
 		return movies, true
 	}
 	return nil, false
 }
 
+// sendCachedDetails sends movies found in cached data on the second channel,
+// and new movies and cached ones with stale fields on the first one
 func sendCachedDetails(refresh bool, done <-chan struct{}, in <-chan movie.Data) (<-chan movie.Data, chan movie.Data) {
 	cached := make(chan movie.Data, mubi.MaxMovies)
 	if refresh {
@@ -61,19 +155,34 @@ func sendCachedDetails(refresh bool, done <-chan struct{}, in <-chan movie.Data)
 	vals, err := movie.ReadFromJSON()
 	if err != nil {
 		debugging.Log().Printf("%v. Could not read cached data, reading from web", err)
+		close(cached)
 		return in, cached
 	}
 
 	new := make(chan movie.Data, mubi.MaxMovies)
+	now := time.Now()
 	go func() {
 		defer close(new)
 		defer close(cached)
 		for md := range in {
-			if val, found := movie.Find(md, vals); found == true {
+			val, found := movie.Find(md, vals)
+			if found && len(val.Failures) > 0 {
+				debugging.Log().Printf("Movie: %s failed in the last update, reading from web\n", md.Title)
+				found = false
+			}
+			if found {
 				// Update days to watch value
 				val.DaysToWatch = md.DaysToWatch
+				val.LeavingAt = md.LeavingAt
+				val.Region = md.Region
+				val.RetrievedAt = md.RetrievedAt
+				out := cached
+				if val.Stale = val.StaleFields(RefreshAfter, now); len(val.Stale) > 0 {
+					debugging.Log().Printf("Movie: %s refreshing %v\n", md.Title, val.Stale)
+					out = new
+				}
 				select {
-				case cached <- val:
+				case out <- val:
 				case <-done:
 					return
 				}
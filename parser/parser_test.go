@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/imdb"
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "parser")
+	if err != nil {
+		panic(err)
+	}
+	debugging.InitLogger(dir, false)
+	movie.JSONPath = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func send(movies ...movie.Data) <-chan movie.Data {
+	in := make(chan movie.Data, len(movies))
+	for _, m := range movies {
+		in <- m
+	}
+	close(in)
+	return in
+}
+
+func titles(c <-chan movie.Data) map[string]movie.Data {
+	got := map[string]movie.Data{}
+	for m := range c {
+		got[m.Title] = m
+	}
+	return got
+}
+
+func TestSendCachedDetails(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-72 * time.Hour)
+	fetched := func(t time.Time) map[string]time.Time {
+		return map[string]time.Time{"MUBI rating": now, "IMDB rating": now, "IMDB ratings num": t}
+	}
+	stored := []movie.Data{
+		{Title: "Stalker", DaysToWatch: 20, ImdbRating: 8.1, Fetched: fetched(now)},
+		{Title: "Mirror", DaysToWatch: 20, ImdbRating: 8, Fetched: fetched(old)},
+		{Title: "Solaris", DaysToWatch: 20, Fetched: fetched(now),
+			Failures: []movie.Failure{{Stage: imdb.Stage, Error: "Movie not found!"}}},
+	}
+	if err := movie.WriteToJSON(stored); err != nil {
+		t.Fatal(err)
+	}
+	RefreshAfter = map[string]time.Duration{"IMDB ratings num": 48 * time.Hour}
+	defer func() { RefreshAfter = map[string]time.Duration{} }()
+
+	done := make(chan struct{})
+	defer close(done)
+	new, cached := sendCachedDetails(false, done, send(
+		movie.Data{Title: "Stalker", DaysToWatch: 3},
+		movie.Data{Title: "Mirror", DaysToWatch: 3},
+		movie.Data{Title: "Solaris", DaysToWatch: 3},
+		movie.Data{Title: "Ivan's Childhood", DaysToWatch: 3},
+	))
+	gotNew, gotCached := titles(new), titles(cached)
+
+	if len(gotCached) != 1 || gotCached["Stalker"].ImdbRating != 8.1 || gotCached["Stalker"].DaysToWatch != 3 {
+		t.Errorf("cached %+v, want fresh Stalker with days left of the lineup", gotCached)
+	}
+	if len(gotNew) != 3 {
+		t.Fatalf("new %+v, want Mirror, Solaris and Ivan's Childhood", gotNew)
+	}
+	mirror := gotNew["Mirror"]
+	if !reflect.DeepEqual(mirror.Stale, []string{"IMDB ratings num"}) || mirror.ImdbRating != 8 || mirror.DaysToWatch != 3 {
+		t.Errorf("stale film %+v, want stored one with stale IMDB ratings num", mirror)
+	}
+	// failed and unknown films are read anew by all stages
+	for _, title := range []string{"Solaris", "Ivan's Childhood"} {
+		if m := gotNew[title]; m.Stale != nil || m.Failures != nil {
+			t.Errorf("%s sent as %+v, want film from the lineup", title, m)
+		}
+	}
+}
+
+func TestSendCachedDetailsRefresh(t *testing.T) {
+	if err := movie.WriteToJSON([]movie.Data{{Title: "Stalker", ImdbRating: 8.1}}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	new, cached := sendCachedDetails(true, done, send(movie.Data{Title: "Stalker"}))
+	if got := titles(cached); len(got) != 0 {
+		t.Errorf("cached %+v on refresh", got)
+	}
+	if got := titles(new); got["Stalker"].ImdbRating != 0 {
+		t.Errorf("new %+v, want Stalker from the lineup", got)
+	}
+}
+
+type noRequests struct{ t *testing.T }
+
+func (n noRequests) RoundTrip(r *http.Request) (*http.Response, error) {
+	n.t.Errorf("unexpected request %s", r.URL)
+	return nil, errors.New("no requests expected")
+}
+
+func TestStaleFieldsOfOtherStage(t *testing.T) {
+	transport, key := http.DefaultTransport, imdb.APIKey
+	http.DefaultTransport, imdb.APIKey = noRequests{t}, "key"
+	defer func() { http.DefaultTransport, imdb.APIKey = transport, key }()
+
+	m := movie.Data{Title: "Stalker", ImdbRating: 8.1, Stale: []string{"MUBI rating"}}
+	done := make(chan struct{})
+	defer close(done)
+	got := <-imdb.SendRatings(done, send(m))
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("got %+v, want film passed on as %+v", got, m)
+	}
+}
+
+func TestSendCachedDetailsWithoutCache(t *testing.T) {
+	path := movie.JSONPath
+	movie.JSONPath = filepath.Join(path, "empty")
+	defer func() { movie.JSONPath = path }()
+
+	done := make(chan struct{})
+	defer close(done)
+	new, cached := sendCachedDetails(false, done, send(movie.Data{Title: "Stalker"}))
+	merged := merge(done, new, cached)
+	deadline := time.After(time.Second)
+	var got []movie.Data
+	for {
+		select {
+		case m, ok := <-merged:
+			if !ok {
+				if len(got) != 1 || got[0].Title != "Stalker" {
+					t.Errorf("merged %+v, want Stalker from the lineup", got)
+				}
+				return
+			}
+			got = append(got, m)
+		case <-deadline:
+			t.Fatal("merge not finished without cached data")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package printer
+
+import (
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/llugin/mubi-parser/watchlist"
+)
+
+var bannerColor = color.New(color.FgHiRed, color.Bold)
+
+// PrintAlerts prints watchlist alerts as a warning banner
+func PrintAlerts(w io.Writer, alerts []watchlist.Alert, noColor bool) {
+	if len(alerts) == 0 {
+		return
+	}
+	color.NoColor = noColor
+
+	regions := map[string]bool{}
+	for _, a := range alerts {
+		regions[a.Movie.Region] = true
+	}
+	lines := []string{"WATCHLIST"}
+	for _, a := range alerts {
+		line := "  * " + a.String()
+		// alerts of several lineups name their region
+		if len(regions) > 1 {
+			line += " (" + a.Movie.Region + ")"
+		}
+		lines = append(lines, line)
+	}
+	width := 0
+	for _, l := range lines {
+		if n := len([]rune(l)); n > width {
+			width = n
+		}
+	}
+
+	border := strings.Repeat("!", width+4)
+	bannerColor.Fprintln(w, border)
+	for _, l := range lines {
+		bannerColor.Fprintf(w, "! %s%s !\n", l, strings.Repeat(" ", width-len([]rune(l))))
+	}
+	bannerColor.Fprintln(w, border)
+}
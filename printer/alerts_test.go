@@ -0,0 +1,41 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/watchlist"
+)
+
+func TestPrintAlerts(t *testing.T) {
+	stalker := movie.Data{Title: "Stalker", DaysToWatch: 2, Region: "GB"}
+	mirror := movie.Data{Title: "Mirror", Region: "GB"}
+
+	var buf bytes.Buffer
+	PrintAlerts(&buf, []watchlist.Alert{{Kind: watchlist.Expiring, Movie: stalker}}, true)
+	if !strings.Contains(buf.String(), "* Stalker leaves MUBI in 2 days !") {
+		t.Errorf("alert of a single lineup:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	other := stalker
+	other.Region = "US"
+	PrintAlerts(&buf, []watchlist.Alert{
+		{Kind: watchlist.Expiring, Movie: stalker},
+		{Kind: watchlist.Arrived, Movie: mirror},
+		{Kind: watchlist.Expiring, Movie: other},
+	}, true)
+	for _, s := range []string{"Stalker leaves MUBI in 2 days (GB)", "Mirror is now showing (GB)", "Stalker leaves MUBI in 2 days (US)"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("%q not in alerts:\n%s", s, buf.String())
+		}
+	}
+
+	buf.Reset()
+	PrintAlerts(&buf, nil, true)
+	if buf.Len() != 0 {
+		t.Errorf("banner without alerts:\n%s", buf.String())
+	}
+}
@@ -1,13 +1,22 @@
 package printer
 
 import (
+	"fmt"
 	"github.com/llugin/mubi-parser/movie"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// CountryStyle - display style of countries: name, code or flag
+var CountryStyle = "name"
+
+// unknown marks values missing because the stage providing them failed
+const unknown = "?"
+
 var columns = []columnRepr{
-	days{}, title{}, director{}, mubi{}, imdb{}, mins{}, year{}, country{}, genre{}}
+	days{}, leaves{}, seen{}, title{}, director{}, mubi{}, imdb{}, points{}, mins{}, year{}, country{}, genre{}}
 
 type columnRepr interface {
 	Header() string
@@ -19,6 +28,29 @@ type days struct{}
 func (d days) Header() string                   { return "Days" }
 func (d days) Value(md *movie.Data) interface{} { return md.DaysToWatch }
 
+type leaves struct{}
+
+func (l leaves) Header() string { return "Leaves" }
+func (l leaves) Value(md *movie.Data) interface{} {
+	if md.LeavingAt.IsZero() {
+		return ""
+	}
+	if left := time.Until(md.LeavingAt); left < 24*time.Hour {
+		return fmt.Sprintf("in %.0fh", math.Ceil(math.Max(left.Hours(), 0)))
+	}
+	return md.LeavingAt.Format("Mon Jan 2")
+}
+
+type seen struct{}
+
+func (s seen) Header() string { return "Seen" }
+func (s seen) Value(md *movie.Data) interface{} {
+	if md.Watched {
+		return "✓"
+	}
+	return ""
+}
+
 type title struct{}
 
 func (t title) Header() string                   { return "Title" }
@@ -27,16 +59,19 @@ func (t title) Value(md *movie.Data) interface{} { return md.Title }
 type director struct{}
 
 func (d director) Header() string                   { return "Director" }
-func (d director) Value(md *movie.Data) interface{} { return md.Director }
+func (d director) Value(md *movie.Data) interface{} { return md.Directors.String() }
 
 type mubi struct{}
 
 func (m mubi) Header() string { return "MUBI" }
 func (m mubi) Value(md *movie.Data) interface{} {
+	if md.Unknown("MUBI rating") {
+		return unknown
+	}
 	var sb strings.Builder
 	sb.WriteString(strconv.FormatFloat(md.MubiRating, 'f', 1, 32))
 	sb.WriteString(" (")
-	sb.WriteString(md.MubiRatingsNumber)
+	sb.WriteString(md.MubiRatingsNumber.String())
 	sb.WriteString(")")
 	return sb.String()
 }
@@ -45,21 +80,36 @@ type imdb struct{}
 
 func (i imdb) Header() string { return "IMDB" }
 func (i imdb) Value(md *movie.Data) interface{} {
+	if md.Unknown("IMDB rating") {
+		return unknown
+	}
 	if md.ImdbRating == 0.0 {
 		return ""
 	}
 	var sb strings.Builder
 	sb.WriteString(strconv.FormatFloat(md.ImdbRating, 'f', 1, 32))
 	sb.WriteString(" (")
-	sb.WriteString(md.ImdbRatingsNumber)
+	sb.WriteString(md.ImdbRatingsNumber.String())
 	sb.WriteString(")")
 	return sb.String()
 }
 
+type points struct{}
+
+func (p points) Header() string { return "Score" }
+func (p points) Value(md *movie.Data) interface{} {
+	return strconv.FormatFloat(md.Score, 'f', 1, 64)
+}
+
 type mins struct{}
 
-func (m mins) Header() string                   { return "Mins" }
-func (m mins) Value(md *movie.Data) interface{} { return md.Mins }
+func (m mins) Header() string { return "Mins" }
+func (m mins) Value(md *movie.Data) interface{} {
+	if md.Unknown("mins") {
+		return unknown
+	}
+	return md.Mins
+}
 
 type year struct{}
 
@@ -69,9 +119,14 @@ func (y year) Value(md *movie.Data) interface{} { return md.Year }
 type country struct{}
 
 func (c country) Header() string                   { return "Country" }
-func (c country) Value(md *movie.Data) interface{} { return md.Country }
+func (c country) Value(md *movie.Data) interface{} { return md.FormatCountries(CountryStyle) }
 
 type genre struct{}
 
-func (g genre) Header() string                   { return "Genre" }
-func (g genre) Value(md *movie.Data) interface{} { return md.Genre }
+func (g genre) Header() string { return "Genre" }
+func (g genre) Value(md *movie.Data) interface{} {
+	if md.Unknown("genre") {
+		return unknown
+	}
+	return md.Genres.String()
+}
@@ -0,0 +1,24 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/score"
+)
+
+// PrintExplain prints how each term contributed to score of movies
+func PrintExplain(w io.Writer, movies []movie.Data, maxLen int) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Title\tMUBI\tIMDB\tRating\tUrgency\tGenre\tCountry\tDecade\tRuntime\tScore\t")
+	for i := range movies {
+		b := score.Compute(&movies[i])
+		fmt.Fprintf(tw, "%v\t%.2f\t%.2f\t%.2f\t%+.2f\t%+.2f\t%+.2f\t%+.2f\t%+.2f\t%.2f\t\n",
+			truncate(movies[i].Title, maxLen), b.Mubi, b.Imdb, b.Rating, b.Urgency,
+			b.Genre, b.Country, b.Decade, b.Runtime, b.Total)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nMUBI and IMDB are Bayesian averages on 0-10 scale, Rating combines them weighted by votes.")
+}
@@ -0,0 +1,110 @@
+package printer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// ExportFormats lists formats supported by Export
+var ExportFormats = []string{"csv", "json", "html"}
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>MUBI - now showing</title>
+</head>
+<body>
+<table>
+<tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr>
+{{range .Movies}}<tr><td>{{.DaysToWatch}}</td><td>{{if not .LeavingAt.IsZero}}{{.LeavingAt.Format "Mon Jan 2 15:04"}}{{end}}</td><td>{{if .Watched}}&#10003;{{end}}</td><td><a href="{{.MubiLink}}">{{.Title}}</a></td><td>{{.Directors}}</td><td>{{printf "%.1f" .MubiRating}} ({{.MubiRatingsNumber}})</td><td>{{if .ImdbRating}}{{printf "%.1f" .ImdbRating}} ({{.ImdbRatingsNumber}}){{end}}</td><td>{{printf "%.1f" .Score}}</td><td>{{.Mins}}</td><td>{{.Year}}</td><td>{{.FormatCountries "name"}}</td><td>{{.Genres}}</td></tr>
+{{end}}</table>
+{{range .Movies}}{{if or .Synopsis .OurTake .PosterURL}}
+<section>
+<h2><a href="{{.MubiLink}}">{{.Title}}</a> ({{.Year}})</h2>
+{{if .PosterURL}}<img src="{{.PosterURL}}" alt="{{.Title}} poster" width="200">
+{{end}}<p>{{.Directors}}{{if .Cast}}; with {{.Cast}}{{end}}</p>
+{{if .Languages}}<p>Language: {{.Languages}}{{if .Subtitles}}; subtitles: {{.Subtitles}}{{end}}</p>
+{{end}}{{if .ContentRating}}<p>Rated: {{.ContentRating}}</p>
+{{end}}{{if .Synopsis}}<p>{{.Synopsis}}</p>
+{{end}}{{if .OurTake}}<blockquote>{{.OurTake}}</blockquote>
+{{end}}{{if .StillURL}}<img src="{{.StillURL}}" alt="{{.Title}} still" width="400">
+{{end}}</section>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// Export writes movies to w in given format
+func Export(w io.Writer, movies []movie.Data, format string) error {
+	switch format {
+	case "csv":
+		return exportCSV(w, movies)
+	case "json":
+		out, err := json.MarshalIndent(movies, "", " ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	case "html":
+		return htmlTemplate.Execute(w, struct {
+			Headers []string
+			Movies  []movie.Data
+		}{getHeaders(), movies})
+	default:
+		return fmt.Errorf("Undefined export format: %s", format)
+	}
+}
+
+func exportCSV(w io.Writer, movies []movie.Data) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"days", "title", "alt title", "director", "country", "year",
+		"genre", "mins", "MUBI rating", "MUBI ratings num", "IMDB rating",
+		"IMDB ratings num", "IMDB id", "appeared", "MUBI link", "cast", "language",
+		"subtitles", "content rating", "synopsis", "our take", "poster", "still",
+		"leaving at"})
+	for _, m := range movies {
+		cw.Write([]string{
+			strconv.Itoa(m.DaysToWatch),
+			m.Title,
+			m.AltTitle,
+			m.Directors.String(),
+			m.Countries.String(),
+			strconv.Itoa(m.Year),
+			m.Genres.String(),
+			strconv.Itoa(m.Mins),
+			strconv.FormatFloat(m.MubiRating, 'f', 1, 32),
+			strconv.Itoa(int(m.MubiRatingsNumber)),
+			strconv.FormatFloat(m.ImdbRating, 'f', 1, 32),
+			strconv.Itoa(int(m.ImdbRatingsNumber)),
+			m.ImdbID,
+			m.DateAppeared,
+			m.MubiLink,
+			m.Cast.String(),
+			m.Languages.String(),
+			m.Subtitles.String(),
+			m.ContentRating,
+			m.Synopsis,
+			m.OurTake,
+			m.PosterURL,
+			m.StillURL,
+			leavingAt(m),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func leavingAt(m movie.Data) string {
+	if m.LeavingAt.IsZero() {
+		return ""
+	}
+	return m.LeavingAt.Format(time.RFC3339)
+}
@@ -0,0 +1,30 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// PrintFailures prints which stages failed for which films, nothing when
+// all films were enriched
+func PrintFailures(w io.Writer, movies []movie.Data) {
+	var lines []string
+	for _, m := range movies {
+		name := m.Title
+		if m.Region != "" {
+			name = fmt.Sprintf("%s (%s)", m.Title, m.Region)
+		}
+		for _, f := range m.Failures {
+			lines = append(lines, fmt.Sprintf("  %s: %s: %s", name, f.Stage, f.Error))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nFailed to get data, marked with ?:")
+	for _, l := range lines {
+		fmt.Fprintln(w, l)
+	}
+}
@@ -1,19 +1,28 @@
 package printer
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/fatih/color"
+	"github.com/llugin/mubi-parser/history"
 	"github.com/llugin/mubi-parser/movie"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"unicode/utf8"
 )
 
+// width of synopsis and editorial paragraphs in details
+const paragraphWidth = 78
+
 var (
-	tabsNo     = len(columns) - 1
+	// index column is printed in front of other columns
+	tabsNo     = len(columns)
 	emptyRow   = strings.Repeat("\t", tabsNo)
 	valuesRow  = strings.Repeat("%v\t", tabsNo) + "%v\n"
-	headersRow = strings.Join(getHeaders(), "\t")
+	headersRow = "#\t" + strings.Join(getHeaders(), "\t")
 	colors     = []*color.Color{color.New(color.FgWhite), color.New(color.FgGreen)}
 )
 
@@ -29,13 +38,24 @@ func PrintTable(movies []movie.Data, noColor bool, maxLen int) {
 	colors[0].Fprintln(w, emptyRow)
 
 	for i, m := range movies {
-		colors[i%2].Fprintf(w, valuesRow, getValues(&m, maxLen)...)
+		values := append([]interface{}{i + 1}, getValues(&m, maxLen)...)
+		colors[i%2].Fprintf(w, valuesRow, values...)
 	}
 	colors[0].Fprintln(w, emptyRow)
 
 	w.Flush()
 }
 
+// Headers returns table column headers
+func Headers() []string {
+	return getHeaders()
+}
+
+// Values returns table row values of movie, truncated to maxLen
+func Values(md *movie.Data, maxLen int) []interface{} {
+	return getValues(md, maxLen)
+}
+
 func getHeaders() []string {
 	headers := []string{}
 	for _, c := range columns {
@@ -75,3 +95,141 @@ func truncate(value interface{}, maxLen int) interface{} {
 
 	return value
 }
+
+// PrintDetails prints all data of a single movie as a formatted block,
+// including its showing windows recorded in history
+func PrintDetails(w io.Writer, md movie.Data, windows []history.Window) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Title:\t%s\n", md.Title)
+	if md.AltTitle != "" {
+		fmt.Fprintf(tw, "Alt title:\t%s\n", md.AltTitle)
+	}
+	fmt.Fprintf(tw, "Director:\t%s\n", md.Directors)
+	fmt.Fprintf(tw, "Country:\t%s (%s)\n", md.Countries, md.FormatCountries("code"))
+	fmt.Fprintf(tw, "Year:\t%d\n", md.Year)
+	fmt.Fprintf(tw, "Genre:\t%s\n", md.Genres)
+	fmt.Fprintf(tw, "Mins:\t%d\n", md.Mins)
+	if len(md.Cast) > 0 {
+		fmt.Fprintf(tw, "Cast:\t%s\n", md.Cast)
+	}
+	if len(md.Languages) > 0 {
+		fmt.Fprintf(tw, "Language:\t%s\n", md.Languages)
+	}
+	if len(md.Subtitles) > 0 {
+		fmt.Fprintf(tw, "Subtitles:\t%s\n", md.Subtitles)
+	}
+	if md.ContentRating != "" {
+		fmt.Fprintf(tw, "Rated:\t%s\n", md.ContentRating)
+	}
+	if md.Unknown("MUBI rating") {
+		fmt.Fprintf(tw, "MUBI:\t%s\n", unknown)
+	} else {
+		fmt.Fprintf(tw, "MUBI:\t%.1f (%s ratings)\n", md.MubiRating, md.MubiRatingsNumber)
+	}
+	if md.Unknown("IMDB rating") {
+		fmt.Fprintf(tw, "IMDB:\t%s\n", unknown)
+	} else if md.ImdbRating != 0.0 {
+		fmt.Fprintf(tw, "IMDB:\t%.1f (%s ratings)\n", md.ImdbRating, md.ImdbRatingsNumber)
+	} else {
+		fmt.Fprintf(tw, "IMDB:\tn/a\n")
+	}
+	if md.ImdbID != "" {
+		fmt.Fprintf(tw, "IMDB id:\t%s\n", md.ImdbID)
+		fmt.Fprintf(tw, "IMDB link:\t%s\n", md.ImdbLink())
+	}
+	fmt.Fprintf(tw, "Appeared:\t%s\n", md.DateAppeared)
+	if !md.LeavingAt.IsZero() {
+		fmt.Fprintf(tw, "Days left:\t%d (leaving %s)\n", md.DaysToWatch, md.LeavingAt.Format("2006-01-02 15:04"))
+	} else if leaving, err := md.ParseDateLeaving(); err == nil {
+		fmt.Fprintf(tw, "Days left:\t%d (leaving %s)\n", md.DaysToWatch, leaving.Format("2006-01-02"))
+	} else {
+		fmt.Fprintf(tw, "Days left:\t%d\n", md.DaysToWatch)
+	}
+	fmt.Fprintf(tw, "MUBI link:\t%s\n", md.MubiLink)
+	if md.Region != "" {
+		fmt.Fprintf(tw, "Region:\t%s\n", md.Region)
+	}
+	if md.PosterURL != "" {
+		fmt.Fprintf(tw, "Poster:\t%s\n", md.PosterURL)
+	}
+	if md.StillURL != "" {
+		fmt.Fprintf(tw, "Still:\t%s\n", md.StillURL)
+	}
+	for i, win := range windows {
+		header := ""
+		if i == 0 {
+			header = "Seen:"
+		}
+		fmt.Fprintf(tw, "%s\t%s - %s\n", header, win.Appeared, win.Leaving)
+	}
+	fields := md.SourceFields()
+	var sources []string
+	for source := range fields {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for i, source := range sources {
+		header := ""
+		if i == 0 {
+			header = "Sources:"
+		}
+		fmt.Fprintf(tw, "%s\t%s: %s\n", header, source, strings.Join(fields[source], ", "))
+	}
+	for i, f := range md.Failures {
+		header := ""
+		if i == 0 {
+			header = "Failed:"
+		}
+		fmt.Fprintf(tw, "%s\t%s: %s\n", header, f.Stage, f.Error)
+	}
+	tw.Flush()
+
+	// long texts do not fit tabwriter cells, print them as paragraphs
+	for _, p := range []struct{ header, text string }{
+		{"Synopsis", md.Synopsis},
+		{"Our take", md.OurTake},
+	} {
+		if p.text != "" {
+			fmt.Fprintf(w, "\n%s:\n%s\n", p.header, wrap(p.text, paragraphWidth))
+		}
+	}
+}
+
+// wrap breaks text into lines no longer than width, between words
+func wrap(text string, width int) string {
+	var sb strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(text) {
+		n := utf8.RuneCountInString(word)
+		if lineLen > 0 && lineLen+1+n > width {
+			sb.WriteString("\n")
+			lineLen = 0
+		} else if lineLen > 0 {
+			sb.WriteString(" ")
+			lineLen++
+		}
+		sb.WriteString(word)
+		lineLen += n
+	}
+	return sb.String()
+}
+
+// PrintDetailsJSON prints all data of a single movie as json
+func PrintDetailsJSON(w io.Writer, md movie.Data, windows []history.Window) error {
+	details := struct {
+		movie.Data
+		Leaving  string           `json:"leaving,omitempty"`
+		ImdbLink string           `json:"IMDB link,omitempty"`
+		Windows  []history.Window `json:"seen"`
+	}{Data: md, ImdbLink: md.ImdbLink(), Windows: windows}
+	if leaving, err := md.ParseDateLeaving(); err == nil {
+		details.Leaving = leaving.Format("2006-01-02")
+	}
+
+	out, err := json.MarshalIndent(details, "", " ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -0,0 +1,76 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// PrintRegions prints films of lineups of regions as a table with days left
+// in each region, films available in most regions first, followed by
+// numbers of films available everywhere and only in a single region
+func PrintRegions(w io.Writer, regions []string, lineups map[string][]movie.Data) {
+	type row struct {
+		movie movie.Data
+		days  map[string]int
+	}
+	rows := map[string]*row{}
+	for _, r := range regions {
+		for _, m := range lineups[r] {
+			// links of regions differ in locale path
+			key := path.Base(m.MubiLink)
+			if rows[key] == nil {
+				rows[key] = &row{movie: m, days: map[string]int{}}
+			}
+			rows[key].days[r] = m.DaysToWatch
+		}
+	}
+
+	var sorted []*row
+	for _, r := range rows {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i].days) != len(sorted[j].days) {
+			return len(sorted[i].days) > len(sorted[j].days)
+		}
+		return sorted[i].movie.Title < sorted[j].movie.Title
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Title\tDirector\tYear\t%s\n", strings.Join(regions, "\t"))
+	everywhere := 0
+	only := map[string]int{}
+	for _, r := range sorted {
+		var cells []string
+		for _, region := range regions {
+			if days, ok := r.days[region]; ok {
+				cells = append(cells, strconv.Itoa(days))
+			} else {
+				cells = append(cells, "-")
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", r.movie.Title, r.movie.Directors, r.movie.Year, strings.Join(cells, "\t"))
+
+		switch len(r.days) {
+		case len(regions):
+			everywhere++
+		case 1:
+			for region := range r.days {
+				only[region]++
+			}
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nIn all regions: %d\n", everywhere)
+	for _, region := range regions {
+		fmt.Fprintf(w, "Only in %s: %d\n", region, only[region])
+	}
+}
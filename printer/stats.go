@@ -0,0 +1,43 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/llugin/mubi-parser/stats"
+)
+
+// PrintStats prints statistics as tables, listing top most frequent values
+// of distributions. Value equal or less than zero stands for all values
+func PrintStats(w io.Writer, s stats.Stats, top int) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Films:\t%d\n", s.Films)
+	fmt.Fprintf(tw, "Avg mins:\t%.0f\n", s.AvgMins)
+	fmt.Fprintf(tw, "Avg MUBI rating:\t%.2f\n", s.AvgMubi)
+	fmt.Fprintf(tw, "Avg IMDB rating:\t%.2f\n", s.AvgImdb)
+	fmt.Fprintf(tw, "MUBI-IMDB correlation:\t%.2f\n", s.Correlation)
+	fmt.Fprintf(tw, "Avg MUBI ratings num:\t%.0f\n", s.AvgMubiVotes)
+	fmt.Fprintf(tw, "Avg IMDB ratings num:\t%.0f\n", s.AvgImdbVotes)
+	tw.Flush()
+
+	for _, dist := range []struct {
+		header string
+		counts []stats.Count
+	}{
+		{"Countries", s.Countries},
+		{"Decades", s.Decades},
+		{"Genres", s.Genres},
+		{"Directors", s.Directors},
+	} {
+		fmt.Fprintf(w, "\n%s\n", dist.header)
+		counts := dist.counts
+		if top > 0 && len(counts) > top {
+			counts = counts[:top]
+		}
+		for _, c := range counts {
+			fmt.Fprintf(tw, "  %s\t%d\t%.0f%%\n", c.Name, c.Films, 100*float64(c.Films)/float64(s.Films))
+		}
+		tw.Flush()
+	}
+}
@@ -0,0 +1,123 @@
+package score
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/llugin/mubi-parser/country"
+	"github.com/llugin/mubi-parser/movie"
+)
+
+const (
+	// number of votes a rating needs to be half trusted
+	mubiConfidence = 500
+	imdbConfidence = 5000
+
+	// ratings assumed for movies without votes, on 0-10 scale
+	mubiPrior = 7.0
+	imdbPrior = 6.5
+)
+
+// Preferences are user-configured tweaks of the score
+type Preferences struct {
+	// Genres, Countries and Decades ("1970s") map values to bonus points
+	Genres    map[string]float64
+	Countries map[string]float64
+	Decades   map[string]float64
+	// MaxMins - movies longer than this lose RuntimePenalty points,
+	// zero stands for no limit
+	MaxMins        int
+	RuntimePenalty float64
+	// Urgency - bonus points for movie leaving today, decreasing linearly
+	// to zero for movie that just appeared
+	Urgency float64
+}
+
+// Prefs - preferences applied by Compute
+var Prefs = Preferences{RuntimePenalty: 1.0, Urgency: 0.5}
+
+// Breakdown shows how each term contributed to movie score
+type Breakdown struct {
+	Mubi    float64
+	Imdb    float64
+	Rating  float64
+	Urgency float64
+	Genre   float64
+	Country float64
+	Decade  float64
+	Runtime float64
+	Total   float64
+}
+
+// Compute calculates score of movie. Ratings of both sites are shrunk
+// towards prior by Bayesian average, so ratings with few votes weigh less,
+// and combined weighted by votes numbers
+func Compute(md *movie.Data) Breakdown {
+	var b Breakdown
+	mubiVotes := float64(md.MubiRatingsNumber)
+	imdbVotes := float64(md.ImdbRatingsNumber)
+	if md.MubiRating == 0.0 {
+		mubiVotes = 0
+	}
+	if md.ImdbRating == 0.0 {
+		imdbVotes = 0
+	}
+
+	// MUBI rates on 0-5 scale
+	b.Mubi = bayes(md.MubiRating*2, mubiVotes, mubiConfidence, mubiPrior)
+	b.Imdb = bayes(md.ImdbRating, imdbVotes, imdbConfidence, imdbPrior)
+	mubiWeight := mubiVotes / (mubiVotes + mubiConfidence)
+	imdbWeight := imdbVotes / (imdbVotes + imdbConfidence)
+	if mubiWeight+imdbWeight > 0 {
+		b.Rating = (b.Mubi*mubiWeight + b.Imdb*imdbWeight) / (mubiWeight + imdbWeight)
+	} else {
+		b.Rating = (mubiPrior + imdbPrior) / 2
+	}
+
+	if md.DaysToWatch > 0 && md.DaysToWatch <= movie.DaysShowing {
+		b.Urgency = Prefs.Urgency * float64(movie.DaysShowing-md.DaysToWatch) / float64(movie.DaysShowing-1)
+	}
+	for _, g := range md.Genres {
+		b.Genre += lookup(Prefs.Genres, g)
+	}
+	for _, c := range md.Countries {
+		for k, v := range Prefs.Countries {
+			if country.Match(k, c) {
+				b.Country += v
+			}
+		}
+	}
+	if md.Year > 0 {
+		b.Decade = lookup(Prefs.Decades, strconv.Itoa(md.Year/10*10)+"s")
+	}
+	if Prefs.MaxMins > 0 && md.Mins > Prefs.MaxMins {
+		b.Runtime = -Prefs.RuntimePenalty
+	}
+
+	b.Total = b.Rating + b.Urgency + b.Genre + b.Country + b.Decade + b.Runtime
+	return b
+}
+
+// Apply sets Score of movies
+func Apply(movies []movie.Data) {
+	for i := range movies {
+		movies[i].Score = Compute(&movies[i]).Total
+	}
+}
+
+func bayes(rating, votes, confidence, prior float64) float64 {
+	return (votes*rating + confidence*prior) / (votes + confidence)
+}
+
+// lookup finds value of key in case insensitive way
+func lookup(prefs map[string]float64, key string) float64 {
+	if key == "" {
+		return 0.0
+	}
+	for k, v := range prefs {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return 0.0
+}
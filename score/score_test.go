@@ -0,0 +1,64 @@
+package score
+
+import (
+	"math"
+	"testing"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func TestCompute(t *testing.T) {
+	defer func(p Preferences) { Prefs = p }(Prefs)
+	Prefs = Preferences{
+		Genres:         map[string]float64{"Documentary": 1, "horror": -1},
+		Countries:      map[string]float64{"fr": 0.5},
+		Decades:        map[string]float64{"1970s": 0.25},
+		MaxMins:        180,
+		RuntimePenalty: 1,
+		Urgency:        0.5,
+	}
+
+	tests := []struct {
+		name string
+		in   movie.Data
+		want Breakdown
+	}{
+		{"no votes", movie.Data{},
+			Breakdown{Mubi: 7, Imdb: 6.5, Rating: 6.75, Total: 6.75}},
+		{"rating without votes counts as none", movie.Data{MubiRating: 5, ImdbRating: 10},
+			Breakdown{Mubi: 7, Imdb: 6.5, Rating: 6.75, Total: 6.75}},
+		{"mubi votes only", movie.Data{MubiRating: 4.5, MubiRatingsNumber: 500},
+			Breakdown{Mubi: 8, Imdb: 6.5, Rating: 8, Total: 8}},
+		{"both sites", movie.Data{MubiRating: 4, MubiRatingsNumber: 500, ImdbRating: 7, ImdbRatingsNumber: 5000},
+			Breakdown{Mubi: 7.5, Imdb: 6.75, Rating: 7.125, Total: 7.125}},
+		{"leaving today", movie.Data{DaysToWatch: 1},
+			Breakdown{Mubi: 7, Imdb: 6.5, Rating: 6.75, Urgency: 0.5, Total: 7.25}},
+		{"just appeared", movie.Data{DaysToWatch: movie.DaysShowing},
+			Breakdown{Mubi: 7, Imdb: 6.5, Rating: 6.75, Total: 6.75}},
+		{"days unknown", movie.Data{DaysToWatch: 0},
+			Breakdown{Mubi: 7, Imdb: 6.5, Rating: 6.75, Total: 6.75}},
+		{"preferences", movie.Data{Genres: movie.List{"Documentary", "Horror"},
+			Countries: movie.List{"France", "Germany"}, Year: 1979, Mins: 200},
+			Breakdown{Mubi: 7, Imdb: 6.5, Rating: 6.75, Country: 0.5, Decade: 0.25, Runtime: -1, Total: 6.5}},
+	}
+	for _, tt := range tests {
+		got := Compute(&tt.in)
+		if !near(got, tt.want) {
+			t.Errorf("%s: Compute() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func near(a, b Breakdown) bool {
+	pairs := [][2]float64{
+		{a.Mubi, b.Mubi}, {a.Imdb, b.Imdb}, {a.Rating, b.Rating}, {a.Urgency, b.Urgency},
+		{a.Genre, b.Genre}, {a.Country, b.Country}, {a.Decade, b.Decade},
+		{a.Runtime, b.Runtime}, {a.Total, b.Total},
+	}
+	for _, p := range pairs {
+		if math.Abs(p[0]-p[1]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
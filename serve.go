@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/feed"
+	"github.com/llugin/mubi-parser/fetch"
+	"github.com/llugin/mubi-parser/history"
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/parser"
+	"github.com/llugin/mubi-parser/score"
+	"github.com/llugin/mubi-parser/workers"
+)
+
+// server serves stored lineup as JSON over HTTP and updates it in the
+// background
+type server struct {
+	conf config
+	// refreshes are requested refreshes, at most one is queued
+	refreshes chan struct{}
+
+	// mu guards state of the background update, which handlers read
+	// instead of package globals the update writes to
+	mu         sync.Mutex
+	refreshing bool
+	lastUpdate time.Time
+	lastErr    string
+	nextUpdate time.Time
+	alerts     []string
+	stages     []string
+	failures   []string
+}
+
+// apiMovie is movie as served by the API, identified by the last element of
+// its MUBI link
+type apiMovie struct {
+	ID string `json:"id"`
+	movie.Data
+	ImdbLink string           `json:"IMDB link,omitempty"`
+	Watched  bool             `json:"watched"`
+	Score    float64          `json:"score"`
+	Windows  []history.Window `json:"seen,omitempty"`
+}
+
+// apiFilm is film recorded in history as served by the API
+type apiFilm struct {
+	ID   string `json:"id"`
+	Link string `json:"MUBI link"`
+	history.Film
+}
+
+func serveCommand() *command {
+	c := newCommand("serve", "", "Serve stored films as JSON over HTTP, updating them in the background")
+	addr := c.flags.String("addr", "localhost:8080", "Address to listen on")
+	every := c.flags.Duration("every", 6*time.Hour, "Update interval of the background refresh, 0 disables it")
+	c.run = func(args []string, conf config) error {
+		s := &server{conf: conf, refreshes: make(chan struct{}, 1)}
+		go s.refreshLoop(*every)
+
+		log.Printf("Serving on http://%s\n", *addr)
+		return http.ListenAndServe(*addr, logRequests(s.handler()))
+	}
+	return c
+}
+
+// handler routes API requests to handlers of s
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /movies", s.movies)
+	mux.HandleFunc("GET /movies/{id}", s.movie)
+	mux.HandleFunc("GET /diff", s.diff)
+	mux.HandleFunc("GET /history", s.history)
+	mux.HandleFunc("GET /feed", s.feed)
+	mux.HandleFunc("GET /status", s.status)
+	mux.HandleFunc("POST /refresh", s.refresh)
+	return mux
+}
+
+// refreshLoop updates stored films at start, every interval and when
+// refresh is requested
+func (s *server) refreshLoop(every time.Duration) {
+	var tick <-chan time.Time
+	if every > 0 {
+		tick = time.NewTicker(every).C
+	}
+	for {
+		s.update(every)
+		select {
+		case <-tick:
+		case <-s.refreshes:
+		}
+	}
+}
+
+func (s *server) update(every time.Duration) {
+	s.mu.Lock()
+	s.refreshing = true
+	s.mu.Unlock()
+
+	// per-run globals are reset by the update and read only by this
+	// goroutine, handlers get their copies below
+	_, err := update(false, s.conf)
+	if err != nil {
+		log.Printf("Update failed: %v\n", err)
+	}
+	var alerts, stages, failures []string
+	for _, a := range parser.Alerts {
+		alerts = append(alerts, a.String())
+	}
+	for _, stat := range workers.Report() {
+		stages = append(stages, stat.String())
+	}
+	for _, f := range fetch.Failures() {
+		failures = append(failures, f.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	s.lastUpdate = time.Now()
+	s.lastErr = ""
+	if err != nil {
+		s.lastErr = err.Error()
+	}
+	s.alerts, s.stages, s.failures = alerts, stages, failures
+	if every > 0 {
+		s.nextUpdate = s.lastUpdate.Add(every)
+	}
+}
+
+// movies serves stored films, sorted and filtered by query parameters
+// named as flags of list: sort, director, genre, country, min-votes and
+// unwatched
+func (s *server) movies(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sv := &sortValue{"days", movie.SortByDays, false}
+	if key := q.Get("sort"); key != "" {
+		if err := sv.Set(key); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	f := &filters{director: q.Get("director"), genre: q.Get("genre"), country: q.Get("country")}
+	if v := q.Get("min-votes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("min-votes: %v", err))
+			return
+		}
+		f.minVotes = n
+	}
+	unwatched := false
+	if v := q.Get("unwatched"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unwatched: %v", err))
+			return
+		}
+		unwatched = b
+	}
+
+	movies, err := storedMovies(unwatched)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	movies = f.apply(movies)
+	sv.sort(movies)
+	out := []apiMovie{}
+	for _, m := range movies {
+		out = append(out, newAPIMovie(m))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// movie serves all details of a stored film with its showing windows
+func (s *server) movie(w http.ResponseWriter, r *http.Request) {
+	movies, err := storedMovies(false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	id := r.PathValue("id")
+	for _, m := range movies {
+		if filmID(m.MubiLink) != id {
+			continue
+		}
+		h, err := history.Read()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		out := newAPIMovie(m)
+		out.Windows = h.Films[m.MubiLink].Windows
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("No stored film %s", id))
+}
+
+// diff serves films that arrived and departed between lineups recorded on
+// dates from and to. To defaults to the most recent lineup and from to
+// the one recorded before to
+func (s *server) diff(w http.ResponseWriter, r *http.Request) {
+	h, err := history.Read()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	d, err := h.Diff(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		From     string    `json:"from"`
+		To       string    `json:"to"`
+		Arrived  []apiFilm `json:"arrived"`
+		Departed []apiFilm `json:"departed"`
+	}{d.From, d.To, apiFilms(h, d.Arrived), apiFilms(h, d.Departed)})
+}
+
+// history serves lineup recorded on date, or all recorded lineups with
+// numbers of their films, arrivals and departures when date is not given
+func (s *server) history(w http.ResponseWriter, r *http.Request) {
+	h, err := history.Read()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if date := r.URL.Query().Get("date"); date != "" {
+		snap, ok := h.Snapshot(date)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("No lineup recorded on %s", date))
+			return
+		}
+		writeJSON(w, http.StatusOK, struct {
+			Date  string    `json:"date"`
+			Films []apiFilm `json:"films"`
+		}{snap.Date, apiFilms(h, snap.Links)})
+		return
+	}
+
+	type lineup struct {
+		Date     string `json:"date"`
+		Films    int    `json:"films"`
+		Arrived  int    `json:"arrived"`
+		Departed int    `json:"departed"`
+	}
+	out := []lineup{}
+	for i, snap := range h.Snapshots {
+		l := lineup{Date: snap.Date, Films: len(snap.Links)}
+		if i > 0 {
+			d, _ := h.Diff(h.Snapshots[i-1].Date, snap.Date)
+			l.Arrived, l.Departed = len(d.Arrived), len(d.Departed)
+		}
+		out = append(out, l)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// feed serves Atom feed of arrivals like feed command, with days and
+// leaving query parameters
+func (s *server) feed(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	o := feed.Options{Region: movie.Region, Days: 30}
+	o.SelfLink = "http://" + r.Host + r.URL.RequestURI()
+	if v := q.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("days: %v", err))
+			return
+		}
+		o.Days = n
+	}
+	if v := q.Get("leaving"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("leaving: %v", err))
+			return
+		}
+		o.Leaving = b
+	}
+
+	var buf bytes.Buffer
+	if err := writeFeed(&buf, o); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// status serves state of the background refresh
+func (s *server) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.state())
+}
+
+// refresh requests an update, served status tells if it is running
+func (s *server) refresh(w http.ResponseWriter, r *http.Request) {
+	select {
+	case s.refreshes <- struct{}{}:
+	default:
+		// an update is queued already
+	}
+	writeJSON(w, http.StatusAccepted, s.state())
+}
+
+type serverState struct {
+	Region     string     `json:"region,omitempty"`
+	Refreshing bool       `json:"refreshing"`
+	LastUpdate *time.Time `json:"last update,omitempty"`
+	LastError  string     `json:"last error,omitempty"`
+	NextUpdate *time.Time `json:"next update,omitempty"`
+	// Alerts, Stages and Failures are of the last update
+	Alerts   []string `json:"alerts,omitempty"`
+	Stages   []string `json:"stages,omitempty"`
+	Failures []string `json:"failed requests,omitempty"`
+}
+
+func (s *server) state() serverState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := serverState{Region: movie.Region, Refreshing: s.refreshing, LastError: s.lastErr,
+		Alerts: s.alerts, Stages: s.stages, Failures: s.failures}
+	if !s.lastUpdate.IsZero() {
+		last := s.lastUpdate
+		st.LastUpdate = &last
+	}
+	if !s.nextUpdate.IsZero() {
+		next := s.nextUpdate
+		st.NextUpdate = &next
+	}
+	return st
+}
+
+// storedMovies reads stored films with their watched state and score, none
+// when no films are stored yet
+func storedMovies(unwatched bool) ([]movie.Data, error) {
+	movies, err := movie.ReadFromJSON()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	score.Apply(movies)
+	return applyWatchLog(movies, unwatched)
+}
+
+func newAPIMovie(m movie.Data) apiMovie {
+	return apiMovie{ID: filmID(m.MubiLink), Data: m, ImdbLink: m.ImdbLink(), Watched: m.Watched, Score: m.Score}
+}
+
+func apiFilms(h *history.Store, links []string) []apiFilm {
+	out := []apiFilm{}
+	for _, l := range links {
+		out = append(out, apiFilm{ID: filmID(l), Link: l, Film: h.Films[l]})
+	}
+	return out
+}
+
+// filmID returns the last element of MUBI link, which is the same in all
+// regions
+func filmID(link string) string {
+	return path.Base(link)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(v); err != nil {
+		debugging.Log().Println(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// logRequests logs requests to debug log
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		debugging.Log().Printf("%s %s\n", r.Method, r.URL)
+		h.ServeHTTP(w, r)
+	})
+}
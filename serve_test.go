@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/debugging"
+	"github.com/llugin/mubi-parser/history"
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/mubi"
+	"github.com/llugin/mubi-parser/watchlist"
+	"github.com/llugin/mubi-parser/watchlog"
+)
+
+// testServer serves a lineup of two films, recorded in history on two
+// dates after a third film left
+func testServer(t *testing.T) http.Handler {
+	dir, err := ioutil.TempDir("", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	debugging.InitLogger(dir, false)
+	movie.JSONPath, history.JSONPath = dir, dir
+	watchlog.JSONPath, watchlist.JSONPath = dir, dir
+	movie.Region = ""
+
+	stalker := movie.Data{Title: "Stalker", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1979,
+		MubiLink: "https://mubi.com/films/stalker", DaysToWatch: 3, MubiRating: 4.5, MubiRatingsNumber: 5000}
+	mirror := movie.Data{Title: "Mirror", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1975,
+		MubiLink: "https://mubi.com/films/mirror", DaysToWatch: 20, MubiRating: 4.4, MubiRatingsNumber: 3000}
+	solaris := movie.Data{Title: "Solaris", Directors: movie.List{"Andrei Tarkovsky"}, Year: 1972,
+		MubiLink: "https://mubi.com/films/solaris", DaysToWatch: 1}
+
+	h, err := history.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Record([]movie.Data{stalker, solaris}, time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local))
+	h.Record([]movie.Data{stalker, mirror}, time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local))
+	if err := h.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if err := movie.WriteToJSON([]movie.Data{stalker, mirror}); err != nil {
+		t.Fatal(err)
+	}
+	return (&server{refreshes: make(chan struct{}, 1)}).handler()
+}
+
+func TestServe(t *testing.T) {
+	srv := testServer(t)
+
+	tests := []struct {
+		method, url string
+		status      int
+		contains    []string
+	}{
+		{"GET", "/movies", 200, []string{`"id": "stalker"`, `"id": "mirror"`}},
+		{"GET", "/movies?sort=nosuchkey", 400, []string{`"error"`}},
+		{"GET", "/movies?min-votes=many", 400, []string{"min-votes"}},
+		{"GET", "/movies?min-votes=4000", 200, []string{`"id": "stalker"`}},
+		{"GET", "/movies?unwatched=maybe", 400, []string{"unwatched"}},
+		{"GET", "/movies/stalker", 200, []string{`"title": "Stalker"`, `"watched": false`}},
+		{"GET", "/movies/solaris", 404, []string{"No stored film solaris"}},
+		{"GET", "/diff", 200, []string{`"from": "2026-10-18"`, `"id": "mirror"`, `"id": "solaris"`}},
+		{"GET", "/diff?from=2020-01-01", 400, []string{"No lineup recorded on 2020-01-01"}},
+		{"GET", "/history", 200, []string{`"date": "2026-10-19"`, `"arrived": 1`}},
+		{"GET", "/history?date=2026-10-18", 200, []string{`"id": "solaris"`}},
+		{"GET", "/history?date=2020-01-01", 404, []string{"No lineup recorded"}},
+		{"GET", "/feed?days=x", 400, []string{"days"}},
+		{"GET", "/feed", 200, []string{"<feed", "MUBI arrivals"}},
+		{"GET", "/status", 200, []string{`"refreshing": false`}},
+		{"POST", "/refresh", 202, []string{`"refreshing"`}},
+		{"GET", "/refresh", 405, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.url, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(rec.Body.String(), s) {
+				t.Errorf("%s %s: %q not in response %s", tt.method, tt.url, s, rec.Body)
+			}
+		}
+	}
+}
+
+func TestServeMoviesFiltered(t *testing.T) {
+	srv := testServer(t)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/movies?sort=days&min-votes=4000", nil))
+	var out []apiMovie
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0].ID != "stalker" {
+		t.Errorf("got %+v, want only stalker", out)
+	}
+}
+
+// mubiPage answers every request with a page listing films in Next.js data
+type mubiPage struct{}
+
+func (mubiPage) RoundTrip(r *http.Request) (*http.Response, error) {
+	page := `<html><head><script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{
+		"films":{"a":{"title":"Stalker","year":1979,"duration":161,"web_url":"https://mubi.com/films/stalker",
+			"directors":[{"name":"Andrei Tarkovsky"}],"genres":["Drama"],"average_rating":4.5,"number_of_ratings":5000}}}}}</script></head><body></body></html>`
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Request: r, Header: http.Header{},
+		Body: ioutil.NopCloser(strings.NewReader(page))}, nil
+}
+
+func TestServeFirstUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	debugging.InitLogger(dir, false)
+	// data directory of the first run, without stored films
+	movie.JSONPath, history.JSONPath = dir, dir
+	watchlog.JSONPath, watchlist.JSONPath = dir, dir
+	mubi.DiagnosticsPath = dir
+	movie.Region = ""
+	transport := http.DefaultTransport
+	http.DefaultTransport = mubiPage{}
+	defer func() { http.DefaultTransport = transport }()
+
+	s := &server{conf: config{SelectorProfile: mubi.DefaultProfileName}, refreshes: make(chan struct{}, 1)}
+	go s.refreshLoop(0)
+	deadline := time.Now().Add(5 * time.Second)
+	for s.state().LastUpdate == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no update recorded with empty data directory")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// a single film suggests changed layout, but all stages ran
+	if st := s.state(); len(st.Stages) != 2 {
+		t.Errorf("state %+v, want update through details and ratings", st)
+	}
+}
@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+// Count is a number of films sharing a value, like a country or a genre
+type Count struct {
+	Name  string `json:"name"`
+	Films int    `json:"films"`
+}
+
+// Stats summarizes a set of films
+type Stats struct {
+	Films        int     `json:"films"`
+	Countries    []Count `json:"countries"`
+	Decades      []Count `json:"decades"`
+	Genres       []Count `json:"genres"`
+	Directors    []Count `json:"directors"`
+	AvgMins      float64 `json:"avg mins"`
+	AvgMubi      float64 `json:"avg MUBI rating"`
+	AvgImdb      float64 `json:"avg IMDB rating"`
+	Correlation  float64 `json:"MUBI-IMDB correlation"`
+	AvgMubiVotes float64 `json:"avg MUBI ratings num"`
+	AvgImdbVotes float64 `json:"avg IMDB ratings num"`
+}
+
+// Compute calculates statistics of movies. Missing values, like zero
+// ratings or runtimes, are left out of averages
+func Compute(movies []movie.Data) Stats {
+	s := Stats{Films: len(movies)}
+	countries := map[string]int{}
+	decades := map[string]int{}
+	genres := map[string]int{}
+	directors := map[string]int{}
+
+	var mins, mubi, imdb, mubiVotes, imdbVotes average
+	var pairsMubi, pairsImdb []float64
+	for _, m := range movies {
+		for _, c := range m.Countries {
+			countries[c]++
+		}
+		if m.Year > 0 {
+			decades[strconv.Itoa(m.Year/10*10)+"s"]++
+		}
+		for _, g := range m.Genres {
+			genres[g]++
+		}
+		for _, d := range m.Directors {
+			directors[d]++
+		}
+
+		mins.add(float64(m.Mins))
+		mubi.add(m.MubiRating)
+		imdb.add(m.ImdbRating)
+		mubiVotes.add(float64(m.MubiRatingsNumber))
+		imdbVotes.add(float64(m.ImdbRatingsNumber))
+		if m.MubiRating > 0 && m.ImdbRating > 0 {
+			pairsMubi = append(pairsMubi, m.MubiRating)
+			pairsImdb = append(pairsImdb, m.ImdbRating)
+		}
+	}
+
+	s.Countries = sorted(countries)
+	s.Decades = sorted(decades)
+	s.Genres = sorted(genres)
+	s.Directors = sorted(directors)
+	s.AvgMins = mins.value()
+	s.AvgMubi = mubi.value()
+	s.AvgImdb = imdb.value()
+	s.AvgMubiVotes = mubiVotes.value()
+	s.AvgImdbVotes = imdbVotes.value()
+	s.Correlation = correlation(pairsMubi, pairsImdb)
+	return s
+}
+
+// average accumulates mean of positive values
+type average struct {
+	sum float64
+	n   int
+}
+
+func (a *average) add(v float64) {
+	if v > 0 {
+		a.sum += v
+		a.n++
+	}
+}
+
+func (a *average) value() float64 {
+	if a.n == 0 {
+		return 0.0
+	}
+	return a.sum / float64(a.n)
+}
+
+// sorted returns counts from the most frequent, ties ordered by name
+func sorted(counts map[string]int) []Count {
+	var out []Count
+	for name, n := range counts {
+		out = append(out, Count{name, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Films != out[j].Films {
+			return out[i].Films > out[j].Films
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// correlation returns Pearson correlation coefficient of x and y
+func correlation(x, y []float64) float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return 0.0
+	}
+	var sx, sy, sxx, syy, sxy float64
+	for i := range x {
+		sx += x[i]
+		sy += y[i]
+		sxx += x[i] * x[i]
+		syy += y[i] * y[i]
+		sxy += x[i] * y[i]
+	}
+	den := math.Sqrt(n*sxx-sx*sx) * math.Sqrt(n*syy-sy*sy)
+	if den == 0 {
+		return 0.0
+	}
+	return (n*sxy - sx*sy) / den
+}
@@ -0,0 +1,470 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/llugin/mubi-parser/movie"
+	"github.com/llugin/mubi-parser/printer"
+	"golang.org/x/term"
+)
+
+const (
+	// number of lines taken by the detail pane
+	detailHeight = 6
+
+	// terminal control sequences
+	altScreenOn  = "\x1b[?1049h"
+	altScreenOff = "\x1b[?1049l"
+	cursorHide   = "\x1b[?25l"
+	cursorShow   = "\x1b[?25h"
+	cursorHome   = "\x1b[H"
+	clearLine    = "\x1b[K"
+	clearBelow   = "\x1b[J"
+	reverseOn    = "\x1b[7m"
+	boldOn       = "\x1b[1m"
+	styleOff     = "\x1b[0m"
+)
+
+// keys recognized by the browser
+const (
+	keyNone = iota
+	keyUp
+	keyDown
+	keyPgUp
+	keyPgDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyCtrlC
+	keyRune
+)
+
+// OnWatch is called with every movie opened from the browser
+var OnWatch func(movie.Data)
+
+type key struct {
+	code int
+	r    rune
+}
+
+type browser struct {
+	all       []movie.Data
+	view      []movie.Data
+	sortIdx   int
+	reversed  bool
+	filter    string
+	filtering bool
+	cursor    int
+	offset    int
+	maxLen    int
+	width     int
+	height    int
+	status    string
+}
+
+// Browse opens full-screen terminal browser over movies. Movies are
+// initially sorted by sortKey, values in table are truncated to maxLen
+func Browse(movies []movie.Data, sortKey string, reversed bool, maxLen int) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("Browse requires an interactive terminal")
+	}
+
+	b := &browser{all: movies, reversed: reversed, maxLen: maxLen}
+	for i, k := range movie.SortKeys {
+		if k == sortKey {
+			b.sortIdx = i
+		}
+	}
+	b.update()
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	fmt.Print(altScreenOn + cursorHide)
+	defer fmt.Print(cursorShow + altScreenOff)
+
+	buf := make([]byte, 16)
+	for {
+		b.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		if quit := b.handle(parseKey(buf[:n])); quit {
+			return nil
+		}
+	}
+}
+
+func parseKey(in []byte) key {
+	switch s := string(in); s {
+	case "\x1b[A", "\x1bOA":
+		return key{code: keyUp}
+	case "\x1b[B", "\x1bOB":
+		return key{code: keyDown}
+	case "\x1b[5~":
+		return key{code: keyPgUp}
+	case "\x1b[6~":
+		return key{code: keyPgDown}
+	case "\x1b[H", "\x1b[1~", "\x1bOH":
+		return key{code: keyHome}
+	case "\x1b[F", "\x1b[4~", "\x1bOF":
+		return key{code: keyEnd}
+	case "\r", "\n":
+		return key{code: keyEnter}
+	case "\x1b":
+		return key{code: keyEsc}
+	case "\x7f", "\x08":
+		return key{code: keyBackspace}
+	case "\x03":
+		return key{code: keyCtrlC}
+	default:
+		r, _ := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError || r < ' ' {
+			return key{code: keyNone}
+		}
+		return key{code: keyRune, r: r}
+	}
+}
+
+// handle reacts on pressed key, returns true if browser should quit
+func (b *browser) handle(k key) bool {
+	b.status = ""
+	if k.code == keyCtrlC {
+		return true
+	}
+
+	if b.filtering {
+		switch k.code {
+		case keyEnter:
+			b.filtering = false
+		case keyEsc:
+			b.filtering = false
+			b.filter = ""
+			b.update()
+		case keyBackspace:
+			if len(b.filter) > 0 {
+				_, size := utf8.DecodeLastRuneInString(b.filter)
+				b.filter = b.filter[:len(b.filter)-size]
+				b.update()
+			}
+		case keyRune:
+			b.filter += string(k.r)
+			b.update()
+		default:
+			b.move(k)
+		}
+		return false
+	}
+
+	switch k.code {
+	case keyEnter:
+		b.watch()
+	case keyEsc:
+		if b.filter != "" {
+			b.filter = ""
+			b.update()
+		}
+	case keyRune:
+		switch k.r {
+		case 'q':
+			return true
+		case 'j':
+			b.move(key{code: keyDown})
+		case 'k':
+			b.move(key{code: keyUp})
+		case 'g':
+			b.move(key{code: keyHome})
+		case 'G':
+			b.move(key{code: keyEnd})
+		case 's':
+			b.sortIdx = (b.sortIdx + 1) % len(movie.SortKeys)
+			b.update()
+		case 'S':
+			b.sortIdx = (b.sortIdx + len(movie.SortKeys) - 1) % len(movie.SortKeys)
+			b.update()
+		case 'r':
+			b.reversed = !b.reversed
+			b.update()
+		case '/':
+			b.filtering = true
+		case 'w':
+			b.watch()
+		}
+	default:
+		b.move(k)
+	}
+	return false
+}
+
+func (b *browser) move(k key) {
+	page := b.listHeight()
+	switch k.code {
+	case keyUp:
+		b.cursor--
+	case keyDown:
+		b.cursor++
+	case keyPgUp:
+		b.cursor -= page
+	case keyPgDown:
+		b.cursor += page
+	case keyHome:
+		b.cursor = 0
+	case keyEnd:
+		b.cursor = len(b.view) - 1
+	}
+	b.clamp()
+}
+
+func (b *browser) clamp() {
+	if b.cursor >= len(b.view) {
+		b.cursor = len(b.view) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	page := b.listHeight()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+page {
+		b.offset = b.cursor - page + 1
+	}
+	if b.offset < 0 {
+		b.offset = 0
+	}
+}
+
+func (b *browser) watch() {
+	if len(b.view) == 0 {
+		return
+	}
+	m := b.view[b.cursor]
+	if err := m.Watch(); err != nil {
+		b.status = err.Error()
+		return
+	}
+	if OnWatch != nil {
+		OnWatch(m)
+	}
+	b.status = fmt.Sprintf("Opened %s", m.Title)
+}
+
+// update filters and sorts movies according to current browser state
+func (b *browser) update() {
+	var selected string
+	if b.cursor < len(b.view) {
+		selected = b.view[b.cursor].MubiLink
+	}
+
+	b.view = b.view[:0]
+	query := strings.ToLower(b.filter)
+	for _, m := range b.all {
+		if matches(&m, query) {
+			b.view = append(b.view, m)
+		}
+	}
+
+	sortFunc, _ := movie.SortFunc(movie.SortKeys[b.sortIdx])
+	sortFunc(b.view)
+	if b.reversed {
+		movie.Reverse(b.view)
+	}
+
+	b.cursor = 0
+	for i, m := range b.view {
+		if m.MubiLink == selected {
+			b.cursor = i
+		}
+	}
+	b.clamp()
+}
+
+func matches(md *movie.Data, query string) bool {
+	if query == "" {
+		return true
+	}
+	for _, field := range []string{md.Title, md.AltTitle, md.Directors.String(), md.Genres.String(), md.Countries.String()} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// listHeight returns number of table rows fitting on screen
+func (b *browser) listHeight() int {
+	// status bar, header, separator and help line
+	h := b.height - detailHeight - 4
+	if h < 1 {
+		return 1
+	}
+	return h
+}
+
+func (b *browser) draw() {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		w, h = 80, 24
+	}
+	b.width, b.height = w, h
+	b.clamp()
+
+	var sb strings.Builder
+	sb.WriteString(cursorHome)
+
+	order := "desc"
+	if b.reversed {
+		order = "asc"
+	}
+	status := fmt.Sprintf(" MUBI  sort: %s (%s)  films: %d/%d",
+		movie.SortKeys[b.sortIdx], order, len(b.view), len(b.all))
+	if b.filtering || b.filter != "" {
+		status += "  filter: " + b.filter
+		if b.filtering {
+			status += "_"
+		}
+	}
+	b.line(&sb, reverseOn+boldOn, status)
+
+	rows := [][]string{printer.Headers()}
+	for i := range b.view {
+		var row []string
+		for _, v := range printer.Values(&b.view[i], b.maxLen) {
+			row = append(row, fmt.Sprint(v))
+		}
+		rows = append(rows, row)
+	}
+	widths := columnWidths(rows)
+
+	b.line(&sb, boldOn, formatRow(rows[0], widths))
+	for i := b.offset; i < b.offset+b.listHeight(); i++ {
+		switch {
+		case i >= len(b.view):
+			b.line(&sb, "", "")
+		case i == b.cursor:
+			b.line(&sb, reverseOn, formatRow(rows[i+1], widths))
+		default:
+			b.line(&sb, "", formatRow(rows[i+1], widths))
+		}
+	}
+
+	b.line(&sb, "", strings.Repeat("─", w))
+	details := b.details()
+	for i := 0; i < detailHeight; i++ {
+		if i < len(details) {
+			b.line(&sb, "", details[i])
+		} else {
+			b.line(&sb, "", "")
+		}
+	}
+
+	help := " ↑/↓ move  s/S sort  r reverse  / filter  enter/w watch  q quit"
+	if b.status != "" {
+		help = " " + b.status
+	}
+	sb.WriteString(reverseOn + pad(help, w) + styleOff + clearBelow)
+	fmt.Print(sb.String())
+}
+
+func (b *browser) details() []string {
+	if len(b.view) == 0 {
+		return []string{" No films match filter"}
+	}
+	m := b.view[b.cursor]
+
+	title := m.Title
+	if m.AltTitle != "" {
+		title += " (" + m.AltTitle + ")"
+	}
+	imdbRating := "n/a"
+	if m.ImdbRating != 0.0 {
+		imdbRating = fmt.Sprintf("%.1f (%s votes)", m.ImdbRating, m.ImdbRatingsNumber)
+	}
+	dates := "Appeared: " + m.DateAppeared
+	if !m.LeavingAt.IsZero() {
+		dates += fmt.Sprintf("  Leaving: %s (%d days left)", m.LeavingAt.Format("2006-01-02 15:04"), m.DaysToWatch)
+	} else if leaving, err := m.ParseDateLeaving(); err == nil {
+		dates += fmt.Sprintf("  Leaving: %s (%d days left)", leaving.Format("2006-01-02"), m.DaysToWatch)
+	}
+
+	return []string{
+		" " + boldOn + title + styleOff,
+		fmt.Sprintf(" %s, %s %d, %d mins", m.Directors, m.Countries, m.Year, m.Mins),
+		" Genre: " + m.Genres.String(),
+		fmt.Sprintf(" MUBI: %.1f (%s votes)  IMDB: %s", m.MubiRating, m.MubiRatingsNumber, imdbRating),
+		" " + dates,
+		" " + m.MubiLink,
+	}
+}
+
+// line writes single screen line in given style, clipped to screen width
+func (b *browser) line(sb *strings.Builder, style, text string) {
+	if style != "" {
+		sb.WriteString(style + pad(text, b.width) + styleOff)
+	} else {
+		sb.WriteString(clip(text, b.width) + clearLine)
+	}
+	sb.WriteString("\r\n")
+}
+
+func columnWidths(rows [][]string) []int {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, v := range row {
+			if n := utf8.RuneCountInString(v); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	return widths
+}
+
+func formatRow(row []string, widths []int) string {
+	var sb strings.Builder
+	for i, v := range row {
+		sb.WriteString(" ")
+		sb.WriteString(pad(v, widths[i]))
+		sb.WriteString("  ")
+	}
+	return sb.String()
+}
+
+// pad fills text with spaces up to width runes, clipping longer text
+func pad(text string, width int) string {
+	text = clip(text, width)
+	if n := utf8.RuneCountInString(text); n < width {
+		text += strings.Repeat(" ", width-n)
+	}
+	return text
+}
+
+// clip cuts text to width runes, ignoring escape sequences
+func clip(text string, width int) string {
+	runes, escape := 0, false
+	for i, r := range text {
+		switch {
+		case r == '\x1b':
+			escape = true
+		case escape:
+			if r >= '@' && r <= '~' && r != '[' {
+				escape = false
+			}
+		default:
+			if runes == width {
+				return text[:i]
+			}
+			runes++
+		}
+	}
+	return text
+}
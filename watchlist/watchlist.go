@@ -0,0 +1,162 @@
+package watchlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+const (
+	jsonFileName = "mubi-watchlist.json"
+
+	// time layout for dates films were added
+	layout = "2006-01-02"
+)
+
+// alert kinds
+const (
+	// Expiring film is leaving within Threshold days
+	Expiring = "expiring"
+	// Arrived film appeared in lineup since previous update
+	Arrived = "arrived"
+)
+
+var (
+	// JSONPath is a path to directory with watchlist json file
+	JSONPath = ""
+	// Threshold - number of days left at or below which film is expiring
+	Threshold = 3
+)
+
+// Item is a film user wants to watch
+type Item struct {
+	Title string `json:"title"`
+	Added string `json:"added"`
+}
+
+// List keeps films user wants to watch, keyed by MUBI link
+type List struct {
+	Films map[string]Item `json:"films"`
+}
+
+// Alert is a notice about watchlisted film
+type Alert struct {
+	Kind  string
+	Movie movie.Data
+}
+
+func (a Alert) String() string {
+	switch a.Kind {
+	case Expiring:
+		if a.Movie.DaysToWatch <= 1 {
+			return fmt.Sprintf("%s leaves MUBI today", a.Movie.Title)
+		}
+		return fmt.Sprintf("%s leaves MUBI in %d days", a.Movie.Title, a.Movie.DaysToWatch)
+	case Arrived:
+		return fmt.Sprintf("%s is now showing", a.Movie.Title)
+	}
+	return a.Movie.Title
+}
+
+func jsonfile() string {
+	return filepath.Join(JSONPath, jsonFileName)
+}
+
+// Read reads watchlist from json file. Missing file results in empty list
+func Read() (*List, error) {
+	l := &List{Films: map[string]Item{}}
+	out, err := ioutil.ReadFile(jsonfile())
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	if err := json.Unmarshal(out, l); err != nil {
+		return l, err
+	}
+	if l.Films == nil {
+		l.Films = map[string]Item{}
+	}
+	return l, nil
+}
+
+// Write writes watchlist to json file
+func (l *List) Write() error {
+	out, err := json.MarshalIndent(l, "", " ")
+	if err != nil {
+		return err
+	}
+	return movie.WriteFile(jsonfile(), out)
+}
+
+// Add adds film identified by MUBI link to watchlist. Empty title is
+// derived from the link
+func (l *List) Add(link, title string, t time.Time) error {
+	if _, ok := l.Films[link]; ok {
+		return fmt.Errorf("%s is already on watchlist", link)
+	}
+	if title == "" {
+		title = path.Base(link)
+	}
+	l.Films[link] = Item{title, t.Format(layout)}
+	return nil
+}
+
+// Remove removes film identified by MUBI link from watchlist
+func (l *List) Remove(link string) error {
+	if _, ok := l.Films[link]; !ok {
+		return fmt.Errorf("%s is not on watchlist", link)
+	}
+	delete(l.Films, link)
+	return nil
+}
+
+// Links returns MUBI links of watchlisted films ordered by title
+func (l *List) Links() []string {
+	var links []string
+	for link := range l.Films {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return l.Films[links[i]].Title < l.Films[links[j]].Title
+	})
+	return links
+}
+
+// Check returns alerts for watchlisted movies that are expiring, or that
+// are missing in previous lineup. Nil previous lineup disables arrival alerts
+func (l *List) Check(movies, previous []movie.Data) []Alert {
+	var alerts []Alert
+	for _, m := range movies {
+		if _, ok := l.Films[m.MubiLink]; !ok {
+			continue
+		}
+		if m.DaysToWatch <= Threshold {
+			alerts = append(alerts, Alert{Expiring, m})
+		}
+		if previous != nil && !contains(previous, m.MubiLink) {
+			alerts = append(alerts, Alert{Arrived, m})
+		}
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].Movie.DaysToWatch < alerts[j].Movie.DaysToWatch
+	})
+	return alerts
+}
+
+func contains(movies []movie.Data, link string) bool {
+	for _, m := range movies {
+		if m.MubiLink == link {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package watchlist
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func TestWriteRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	JSONPath = dir
+
+	l, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if err := l.Add("https://mubi.com/films/stalker", "", added); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Add("https://mubi.com/films/stalker", "Stalker", added); err == nil {
+		t.Errorf("second Add of the same film succeeded")
+	}
+	if err := l.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != jsonFileName {
+		t.Errorf("files after Write: %v, want only %s", files, jsonFileName)
+	}
+	read, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Item{Title: "stalker", Added: "2026-10-19"}
+	if got := read.Films["https://mubi.com/films/stalker"]; got != want {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+	if err := read.Remove("https://mubi.com/films/mirror"); err == nil {
+		t.Errorf("Remove of film not on watchlist succeeded")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	l := &List{Films: map[string]Item{"stalker": {}, "mirror": {}, "solaris": {}}}
+	movies := []movie.Data{
+		{Title: "Stalker", MubiLink: "stalker", DaysToWatch: 2},
+		{Title: "Mirror", MubiLink: "mirror", DaysToWatch: 20},
+		{Title: "Solaris", MubiLink: "solaris", DaysToWatch: Threshold + 1},
+		{Title: "Ivan's Childhood", MubiLink: "ivan", DaysToWatch: 1},
+	}
+	previous := []movie.Data{{MubiLink: "stalker"}, {MubiLink: "solaris"}}
+
+	tests := []struct {
+		name     string
+		previous []movie.Data
+		want     string
+	}{
+		{"with previous lineup", previous, "[{expiring Stalker} {arrived Mirror}]"},
+		{"without previous lineup", nil, "[{expiring Stalker}]"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, a := range l.Check(movies, tt.previous) {
+			got = append(got, fmt.Sprintf("{%s %s}", a.Kind, a.Movie.Title))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s: Check() = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
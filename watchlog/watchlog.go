@@ -0,0 +1,142 @@
+package watchlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+const (
+	jsonFileName = "mubi-watchlog.json"
+
+	// time layout for log timestamps
+	layout = "2006-01-02 15:04"
+
+	// MaxRating is the highest personal rating
+	MaxRating = 10.0
+)
+
+// JSONPath is a path to directory with watch log json file
+var JSONPath = ""
+
+// Entry is a personal record of a single film
+type Entry struct {
+	Title    string   `json:"title"`
+	Director string   `json:"director"`
+	Opened   []string `json:"opened,omitempty"`
+	Watched  string   `json:"watched,omitempty"`
+	Rating   float64  `json:"rating,omitempty"`
+	Note     string   `json:"note,omitempty"`
+}
+
+// Log keeps personal entries keyed by MUBI link
+type Log struct {
+	Films map[string]Entry `json:"films"`
+}
+
+func jsonfile() string {
+	return filepath.Join(JSONPath, jsonFileName)
+}
+
+// Read reads watch log from json file. Missing file results in empty log
+func Read() (*Log, error) {
+	l := &Log{Films: map[string]Entry{}}
+	out, err := ioutil.ReadFile(jsonfile())
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	if err := json.Unmarshal(out, l); err != nil {
+		return l, err
+	}
+	if l.Films == nil {
+		l.Films = map[string]Entry{}
+	}
+	return l, nil
+}
+
+// Write writes watch log to json file
+func (l *Log) Write() error {
+	out, err := json.MarshalIndent(l, "", " ")
+	if err != nil {
+		return err
+	}
+	return movie.WriteFile(jsonfile(), out)
+}
+
+func (l *Log) entry(m movie.Data) Entry {
+	e := l.Films[m.MubiLink]
+	e.Title, e.Director = m.Title, m.Directors.String()
+	return e
+}
+
+// Opened records that movie page was opened at t
+func (l *Log) Opened(m movie.Data, t time.Time) {
+	e := l.entry(m)
+	e.Opened = append(e.Opened, t.Format(layout))
+	l.Films[m.MubiLink] = e
+}
+
+// MarkWatched marks movie as watched at t, with optional rating and note.
+// Rating equal to zero stands for no rating
+func (l *Log) MarkWatched(m movie.Data, t time.Time, rating float64, note string) error {
+	if rating < 0 || rating > MaxRating {
+		return fmt.Errorf("Rating must be between 0 and %.0f", MaxRating)
+	}
+	e := l.entry(m)
+	e.Watched = t.Format(layout)
+	e.Rating = rating
+	e.Note = note
+	l.Films[m.MubiLink] = e
+	return nil
+}
+
+// Unmark removes watched state, rating and note of movie
+func (l *Log) Unmark(m movie.Data) error {
+	e, ok := l.Films[m.MubiLink]
+	if !ok || e.Watched == "" {
+		return fmt.Errorf("%s is not marked as watched", m.Title)
+	}
+	e.Watched, e.Rating, e.Note = "", 0.0, ""
+	if len(e.Opened) == 0 {
+		delete(l.Films, m.MubiLink)
+	} else {
+		l.Films[m.MubiLink] = e
+	}
+	return nil
+}
+
+// Apply sets watched state of movies according to log
+func (l *Log) Apply(movies []movie.Data) {
+	for i := range movies {
+		movies[i].Watched = l.Films[movies[i].MubiLink].Watched != ""
+	}
+}
+
+// Entries returns log entries from the most recently active one
+func (l *Log) Entries() []Entry {
+	var entries []Entry
+	for _, e := range l.Films {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastActive() > entries[j].lastActive()
+	})
+	return entries
+}
+
+func (e Entry) lastActive() string {
+	last := e.Watched
+	if n := len(e.Opened); n > 0 && e.Opened[n-1] > last {
+		last = e.Opened[n-1]
+	}
+	return last
+}
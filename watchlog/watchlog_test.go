@@ -0,0 +1,73 @@
+package watchlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/llugin/mubi-parser/movie"
+)
+
+func TestWriteRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	JSONPath = dir
+
+	stalker := movie.Data{Title: "Stalker", Directors: movie.List{"Andrei Tarkovsky"}, MubiLink: "https://mubi.com/films/stalker"}
+	l, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Opened(stalker, time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC))
+	if err := l.MarkWatched(stalker, time.Date(2026, 10, 19, 22, 30, 0, 0, time.UTC), 9, "zone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != jsonFileName {
+		t.Errorf("files after Write: %v, want only %s", files, jsonFileName)
+	}
+	read, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Entry{Title: "Stalker", Director: "Andrei Tarkovsky", Opened: []string{"2026-10-18 20:00"},
+		Watched: "2026-10-19 22:30", Rating: 9, Note: "zone"}
+	if got := read.Films[stalker.MubiLink]; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+}
+
+func TestMarkWatched(t *testing.T) {
+	m := movie.Data{Title: "Mirror", MubiLink: "https://mubi.com/films/mirror"}
+	for _, tt := range []struct {
+		rating float64
+		ok     bool
+	}{{0, true}, {7.5, true}, {MaxRating, true}, {-1, false}, {MaxRating + 1, false}} {
+		l := &Log{Films: map[string]Entry{}}
+		if err := l.MarkWatched(m, time.Now(), tt.rating, ""); (err == nil) != tt.ok {
+			t.Errorf("MarkWatched rating %v: error %v, want ok %v", tt.rating, err, tt.ok)
+		}
+	}
+
+	l := &Log{Films: map[string]Entry{}}
+	l.MarkWatched(m, time.Now(), 0, "")
+	if err := l.Unmark(m); err != nil {
+		t.Errorf("Unmark: %v", err)
+	}
+	if _, ok := l.Films[m.MubiLink]; ok {
+		t.Errorf("entry without opened dates kept after Unmark")
+	}
+	if err := l.Unmark(m); err == nil {
+		t.Errorf("Unmark of unwatched film succeeded")
+	}
+}
@@ -0,0 +1,142 @@
+package workers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Stat is throughput of a single pipeline stage
+type Stat struct {
+	Stage   string
+	Workers int
+	Items   int
+	// Elapsed is time from the first item received to the last one sent
+	Elapsed time.Duration
+	// Busy is time workers spent processing items, summed over workers
+	Busy time.Duration
+}
+
+func (s Stat) String() string {
+	perSec, busy := 0.0, 0.0
+	if s.Elapsed > 0 {
+		perSec = float64(s.Items) / s.Elapsed.Seconds()
+		busy = 100 * s.Busy.Seconds() / (s.Elapsed.Seconds() * float64(s.Workers))
+	}
+	return fmt.Sprintf("%s: %d items in %.1f s, %.2f items/s, %d workers %.0f%% busy",
+		s.Stage, s.Items, s.Elapsed.Seconds(), perSec, s.Workers, busy)
+}
+
+var (
+	stats   []*Stat
+	statsMu sync.Mutex
+)
+
+// Report returns throughput of stages run since the last Reset
+func Report() []Stat {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	var out []Stat
+	for _, s := range stats {
+		out = append(out, *s)
+	}
+	return out
+}
+
+// Reset forgets throughput of stages run so far
+func Reset() {
+	statsMu.Lock()
+	stats = nil
+	statsMu.Unlock()
+}
+
+// Map processes items from in with fn in n worker goroutines and sends
+// results in order of items. Throughput of the stage is kept for Report
+func Map[T any](done <-chan struct{}, in <-chan T, n int, stage string, fn func(T) T) <-chan T {
+	if n < 1 {
+		n = 1
+	}
+	type item struct {
+		i int
+		v T
+	}
+	stat := &Stat{Stage: stage, Workers: n}
+	statsMu.Lock()
+	stats = append(stats, stat)
+	statsMu.Unlock()
+
+	jobs := make(chan item)
+	results := make(chan item)
+	out := make(chan T, cap(in))
+	var start time.Time
+
+	go func() {
+		defer close(jobs)
+		i := 0
+		for v := range in {
+			if i == 0 {
+				start = time.Now()
+			}
+			select {
+			case jobs <- item{i, v}:
+			case <-done:
+				return
+			}
+			i++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for w := 0; w < n; w++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				began := time.Now()
+				r := fn(j.v)
+				statsMu.Lock()
+				stat.Busy += time.Since(began)
+				statsMu.Unlock()
+				select {
+				case results <- item{j.i, r}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer close(out)
+		// results wait here until all items before them are sent
+		pending := map[int]T{}
+		next := 0
+		for r := range results {
+			pending[r.i] = r.v
+			for {
+				v, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				select {
+				case out <- v:
+				case <-done:
+					return
+				}
+				next++
+			}
+		}
+		statsMu.Lock()
+		stat.Items = next
+		if next > 0 {
+			stat.Elapsed = time.Since(start)
+		}
+		statsMu.Unlock()
+	}()
+	return out
+}
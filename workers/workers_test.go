@@ -0,0 +1,103 @@
+package workers
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func source(n int) <-chan int {
+	in := make(chan int, n)
+	for i := 0; i < n; i++ {
+		in <- i
+	}
+	close(in)
+	return in
+}
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   int
+		workers int
+	}{
+		{"no items", 0, 4},
+		{"single worker", 10, 1},
+		{"more workers than items", 3, 8},
+		{"many items", 100, 4},
+		{"zero workers run one", 5, 0},
+	}
+	for _, tt := range tests {
+		Reset()
+		done := make(chan struct{})
+		var running, most int32
+		out := Map(done, source(tt.items), tt.workers, tt.name, func(i int) int {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			// later items finish first often, results keep order anyway
+			time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+			atomic.AddInt32(&running, -1)
+			return i * 2
+		})
+
+		var got []int
+		for v := range out {
+			got = append(got, v)
+		}
+		close(done)
+		if len(got) != tt.items {
+			t.Errorf("%s: %d results, want %d", tt.name, len(got), tt.items)
+		}
+		for i, v := range got {
+			if v != i*2 {
+				t.Errorf("%s: result %d is %d, want %d", tt.name, i, v, i*2)
+				break
+			}
+		}
+		workers := tt.workers
+		if workers < 1 {
+			workers = 1
+		}
+		if int(most) > workers {
+			t.Errorf("%s: %d items processed at once by %d workers", tt.name, most, workers)
+		}
+
+		stats := Report()
+		if len(stats) != 1 || stats[0].Stage != tt.name || stats[0].Items != tt.items || stats[0].Workers != workers {
+			t.Errorf("%s: stats %+v", tt.name, stats)
+		}
+	}
+}
+
+func TestMapDone(t *testing.T) {
+	done := make(chan struct{})
+	out := Map(done, source(50), 4, "cancelled", func(i int) int { return i })
+	<-out
+	close(done)
+	// workers stop without the rest of results being read
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-out:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("output not closed after done")
+		}
+	}
+}
+
+func TestStatString(t *testing.T) {
+	s := Stat{Stage: "MUBI details", Workers: 2, Items: 10, Elapsed: 5 * time.Second, Busy: 5 * time.Second}
+	want := "MUBI details: 10 items in 5.0 s, 2.00 items/s, 2 workers 50% busy"
+	if got := s.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}